@@ -1,5 +1,9 @@
 module github.com/sudosays/hydra
 
-go 1.15
+go 1.16
 
-require github.com/gdamore/tcell/v2 v2.1.0
+require (
+	github.com/BurntSushi/toml v1.3.2
+	github.com/gdamore/tcell/v2 v2.1.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -5,12 +5,14 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"github.com/sudosays/hydra/internal/git"
 	"github.com/sudosays/hydra/pkg/data/hugo"
 	"io/ioutil"
 	"math"
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -19,31 +21,85 @@ import (
 type HydraConfig struct {
 	Extension string        `json:"extension"`
 	Editor    EditorCommand `json:"editor"`
+	Hugo      string        `json:"hugo"`
+	Browser   string        `json:"browser"`
 	Sites     []HugoSite    `json:"sites"`
 }
 
 // HugoSite contains the information for a hugo site listed in the config
 type HugoSite struct {
-	Name string `json:"name"`
-	Path string `json:"path"`
+	Name        string        `json:"name"`
+	Path        string        `json:"path"`
+	UseHugoList bool          `json:"useHugoList"`
+	Section     string        `json:"section"`
+	Slug        SlugConfig    `json:"slug"`
+	Deploy      DeployConfig  `json:"deploy"`
+	Preview     PreviewConfig `json:"preview"`
 }
 
+// DeployConfig sets where the deploy command pushes the built site. The remote
+// defaults to "origin" and the branch to "gh-pages".
+type DeployConfig struct {
+	Remote  string `json:"remote"`
+	Branch  string `json:"branch"`
+	Message string `json:"message"`
+}
+
+// PreviewConfig sets how the preview command runs `hugo server` for a site.
+// The port defaults to 1313.
+type PreviewConfig struct {
+	Port   int  `json:"port"`
+	Drafts bool `json:"drafts"`
+	Future bool `json:"future"`
+}
+
+// SlugConfig sets how the file names of new posts are made from their titles.
+// OnCollision is "refuse" (the default) or "suffix" to number the file name
+// when a post with the same name exists.
+type SlugConfig struct {
+	MaxLength   int    `json:"maxLength"`
+	DatePrefix  bool   `json:"datePrefix"`
+	OnCollision string `json:"onCollision"`
+}
+
+// EditorCommand is the editor posts are opened in. LineArgs tells hydra how to
+// open a file at a line, e.g. "+{line}" for vim, or "--goto {file}:{line}" for
+// VS Code. When it contains {file} it replaces the path of the post as well.
 type EditorCommand struct {
-	Command string `json:"command"`
-	Args    string `json:"args"`
+	Command  string `json:"command"`
+	Args     string `json:"args"`
+	LineArgs string `json:"lineArgs"`
 }
 
-func check(e error) {
-	if e != nil {
-		panic(e)
-	}
+// fatal reports an error that hydra cannot recover from, such as a missing
+// config file on start up, and exits.
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "hydra: %s\n", err)
+	exit(1)
+}
+
+// exit stops the preview server, so that no hugo process outlives hydra, and
+// exits with code. Every way out of hydra goes through it.
+func exit(code int) {
+	stopPreview()
+	os.Exit(code)
 }
 
 var config HydraConfig
 
+var siteFlag *string
+var tuiFlag *bool
+
 var currentPageIndex int = 1 // For pagination purposes
 var numPages int = 0
 
+// stdin is shared by all prompts so that buffered input is not lost between
+// them.
+var stdin = bufio.NewReader(os.Stdin)
+
+// statusMessage is shown below the post list after a command has run.
+var statusMessage string
+
 const maxItemsPerPage int = 10
 
 func init() {
@@ -53,146 +109,566 @@ func init() {
 
 	// Parse command line flags if any
 	configFilePath := flag.String("config", defaultConfigFilePath, "Path to a config file")
+	siteFlag = flag.String("site", "", "Name or number of the site to open")
+	tuiFlag = flag.Bool("tui", false, "Use the interactive terminal interface instead of the REPL")
+	watchFlag = flag.Bool("watch", true, "Reload the post list when files in the content directory change")
+	flag.Parse()
 
 	config, err = readConfig(*configFilePath)
-	check(err)
+	if err != nil {
+		fatal(err)
+	}
+	if config.Hugo != "" {
+		hugo.Binary = config.Hugo
+	}
+	if len(config.Sites) == 0 {
+		fatal(fmt.Errorf("no sites are listed in the config file %s", *configFilePath))
+	}
+
+	statePath = path.Join(path.Dir(*configFilePath), "hydra-state.json")
 
 }
 
 func main() {
 
 	clearTerm()
+	if *tuiFlag {
+		// Warnings of the hugo package, from loading the site on, would
+		// be written over the screen of the TUI.
+		hugo.Logger.SetOutput(statusWriter{})
+		hugo.Logger.SetFlags(0)
+	}
 	// Setup to parse args
-	blog := hugo.Load(config.Sites[0].Path)
+	site, err := initialSite(*siteFlag)
+	if err != nil {
+		fatal(err)
+	}
+	blog, err := switchSite(site)
+	if err != nil {
+		fatal(err)
+	}
 
-	// Calculate the number of pages
-	numPages = int(math.Ceil(float64(len(blog.Posts)) / float64(maxItemsPerPage)))
+	if *tuiFlag {
+		if err := runTUI(blog); err != nil {
+			fatal(err)
+		}
+		exit(0)
+	}
 
 	// main REPL
 	for {
+		blog = applyChanges(blog)
+		fmt.Printf("Site: %s (%s)\n\n", config.Sites[activeSite].Name, blog.Path)
 		printPostList(blog)
+		if statusMessage != "" {
+			fmt.Println(statusMessage)
+			statusMessage = ""
+		}
 		command := promptUser("\nWhat would you like to do?\n" +
-			"Commands: [a]dd [e]dit [d]elete [n]ext/[p]rev page [q]uit\n> ")
+			"Commands: [a]dd [e]dit [d]elete [o]pen [n]ext/[p]rev page publish/unpublish [s]ort [f]ilter search mv rename files sections site tax trash sync deploy preview [q]uit\n> ")
 		blog = parseCommand(command, blog)
+		saveIndex(blog)
+		gitChanged()
 		clearTerm()
 	}
 }
 
+// loadSite reads the posts of a site, either natively or through `hugo list`
+// if the site asks for it.
+func loadSite(site HugoSite) (hugo.Blog, error) {
+	if site.UseHugoList {
+		return hugo.LoadWithHugo(site.Path)
+	}
+	return hugo.Load(site.Path)
+}
+
 func readConfig(path string) (HydraConfig, error) {
 	conf := HydraConfig{}
-	configFile, err := os.Open(path)
-	check(err)
-	byteValue, err := ioutil.ReadAll(configFile)
-	check(err)
-	//fmt.Printf("%s", byteValue)
-	err = json.Unmarshal(byteValue, &conf)
-	check(err)
-	//fmt.Printf("Config is: %+v\n", conf)
-	configFile.Close()
+	byteValue, err := ioutil.ReadFile(path)
+	if err != nil {
+		return conf, fmt.Errorf("reading the config file: %w", err)
+	}
+	if err := json.Unmarshal(byteValue, &conf); err != nil {
+		return conf, fmt.Errorf("the config file %s is not valid JSON: %w", path, err)
+	}
+	// Relative site paths are relative to where hydra was started.
+	for i, site := range conf.Sites {
+		abs, err := filepath.Abs(site.Path)
+		if err != nil {
+			return conf, err
+		}
+		conf.Sites[i].Path = abs
+	}
 	return conf, nil
 }
 
-func startEditor(path string) {
-	editorCmd := exec.Command(config.Editor.Command, config.Editor.Args, path)
+func startEditor(path string) error {
+	return startEditorAt(path, 0)
+}
+
+// lineArgEditors are editors that go to a line given as `+<line>`, which is
+// used when the config does not set lineArgs.
+var lineArgEditors = map[string]bool{
+	"vi": true, "vim": true, "nvim": true, "nano": true, "emacs": true,
+	"emacsclient": true, "micro": true, "kak": true, "joe": true,
+}
+
+// startEditorAt opens the file in the editor at a line, if line is not 0 and
+// the editor supports it.
+func startEditorAt(path string, line int) error {
+	args := []string{config.Editor.Args, path}
+	lineArgs := config.Editor.LineArgs
+	if lineArgs == "" && lineArgEditors[filepath.Base(config.Editor.Command)] {
+		lineArgs = "+{line}"
+	}
+	if line > 0 && lineArgs != "" {
+		replacer := strings.NewReplacer("{line}", strconv.Itoa(line), "{file}", path)
+		args = []string{config.Editor.Args}
+		for _, arg := range strings.Fields(lineArgs) {
+			args = append(args, replacer.Replace(arg))
+		}
+		if !strings.Contains(lineArgs, "{file}") {
+			args = append(args, path)
+		}
+	}
+
+	editorCmd := exec.Command(config.Editor.Command, args...)
 	editorCmd.Stdin = os.Stdin
 	editorCmd.Stdout = os.Stdout
-	err := editorCmd.Run()
-	check(err)
+	if err := editorCmd.Run(); err != nil {
+		return fmt.Errorf("running the editor %s: %w", config.Editor.Command, err)
+	}
+	return nil
 }
 
+// genPostList builds the rows of the post list for the posts that pass the
+// current view, in the order of the view. Each row keeps the number of the
+// post in blog.Posts.
 func genPostList(blog hugo.Blog) ([]string, [][]string) {
 
 	posts := blog.Posts
-	headings := []string{"#", "Date", "Draft", "Title"}
+	headings := []string{"#", "Date", "Draft", "Section", "Title", "Tags"}
 	var postList [][]string
 
-	for i, post := range posts {
+	// Only show the git state of posts when the site is in a repo.
+	gitStatus, gitErr := repoStatus(blog)
+	if gitErr == nil {
+		headings = append(headings, "Git")
+	}
+
+	for _, i := range view.apply(posts) {
+		post := posts[i]
 		draftStatus := "False"
 		if post.Draft {
 			draftStatus = "True"
 		}
 		datetime, _ := time.Parse(time.RFC3339, post.Date)
 		date := datetime.Format("2006/01/02")
-		postList = append(
-			postList,
-			[]string{fmt.Sprintf("%d", i+1), date, draftStatus, post.Title})
+		row := []string{fmt.Sprintf("%d", i+1), date, draftStatus, post.Section, post.Title, strings.Join(post.Tags, ", ")}
+		if gitErr == nil {
+			row = append(row, postGitState(gitStatus, blog, post))
+		}
+		postList = append(postList, row)
 	}
 
 	return headings, postList
 }
 
-func parseCommand(cmd string, blog hugo.Blog) hugo.Blog {
-	parts := strings.Split(cmd, " ")
-	switch parts[0][0] {
-	case 'e':
-		index := -1
-		if len(parts) > 1 {
-			// startEditor
-			i, err := strconv.Atoi(strings.Trim(parts[1], "\n\r "))
-			check(err)
-			index = i
+// gitState caches the git status of the active site, so that drawing the post
+// list does not run git every time. It is read again for another site, after
+// a command, and when the watcher finds changes.
+var gitState struct {
+	path   string
+	status git.RepoStatus
+	err    error
+	stale  bool
+}
+
+// repoStatus returns the git status of the site, from the cache unless it is
+// out of date.
+func repoStatus(blog hugo.Blog) (git.RepoStatus, error) {
+	if gitState.stale || gitState.path != blog.Path {
+		gitState.status, gitState.err = git.Status(blog.Path)
+		gitState.path, gitState.stale = blog.Path, false
+	}
+	return gitState.status, gitState.err
+}
+
+// gitChanged marks the cached git status as out of date.
+func gitChanged() {
+	gitState.stale = true
+}
 
+func parseCommand(cmd string, blog hugo.Blog) hugo.Blog {
+	parts := splitArgs(cmd)
+	if len(parts) == 0 {
+		return blog
+	}
+	switch parts[0] {
+	case "e", "edit":
+		post, err := promptPost(parts, "Enter a post number to edit:\n> ", blog)
+		if err == nil {
+			err = startEditor(filepath.Join(blog.Path, post.Path))
+		}
+		if err == nil {
+			err = blog.Refresh(post.Path)
+		}
+		if err != nil {
+			statusMessage = err.Error()
+		}
+	case "a", "add":
+		opts, err := addOptions(parts[1:])
+		if err != nil {
+			statusMessage = err.Error()
+			break
+		}
+		if opts.Title == "" {
+			opts.Title = strings.TrimSpace(promptUser("Please enter a title for the post:\n> "))
+		}
+		fmt.Printf("Attempting to create post with title: %s\n", opts.Title)
+		post, err := blog.NewPost(opts)
+		if err == nil {
+			err = startEditor(filepath.Join(blog.Path, post.Path))
+		}
+		if err == nil {
+			err = blog.Refresh(post.Path)
+		}
+		if err != nil {
+			statusMessage = err.Error()
+		}
+	case "d", "delete":
+		// Ask for confirmation first. Deleted posts go to the trash.
+		post, err := promptPost(parts, "Enter a post number to delete:\n> ", blog)
+		if err != nil {
+			statusMessage = err.Error()
+			break
+		}
+		warn := "You are about to delete the post titled '%s'.\nIt can be restored with `trash restore`.\nProceed? [y/N] Default: N.\n> "
+		if confirmed(promptUser(fmt.Sprintf(warn, post.Title))) {
+			if err := blog.DeletePost(post.Path); err != nil {
+				statusMessage = err.Error()
+			} else {
+				statusMessage = fmt.Sprintf("Moved '%s' to the trash", post.Title)
+			}
+		}
+	case "o", "open":
+		post, err := promptPost(parts, "Enter a post number to open in the browser:\n> ", blog)
+		if err == nil {
+			statusMessage, err = openPost(blog, post)
+		}
+		if err != nil {
+			statusMessage = err.Error()
+		}
+	case "sections":
+		listing, err := sectionList(blog)
+		if err != nil {
+			statusMessage = err.Error()
+			break
+		}
+		fmt.Print(listing)
+		promptUser("Press Enter to return to the post list")
+	case "search", "/":
+		// Pass the query on as typed, so quotes in it are kept.
+		search := strings.TrimSpace(strings.TrimSpace(cmd)[len(parts[0]):])
+		listing, hits, err := searchCommand(search, blog)
+		if err == nil {
+			fmt.Print(listing)
+			err = openSearchHit(hits, &blog)
+		}
+		if err != nil {
+			statusMessage = err.Error()
+		}
+	case "mv", "move", "rename":
+		if err := movePost(parts, &blog); err != nil {
+			statusMessage = err.Error()
+		}
+	case "files":
+		post, err := promptPost(parts, "Enter a post number to list the resources of:\n> ", blog)
+		listing := ""
+		if err == nil {
+			listing, err = resourceList(post)
+		}
+		if err != nil {
+			statusMessage = err.Error()
+			break
+		}
+		fmt.Print(listing)
+		promptUser("Press Enter to return to the post list")
+	case "publish", "unpublish":
+		post, err := promptPost(parts, "Enter a post number to "+parts[0]+":\n> ", blog)
+		if err != nil {
+			statusMessage = err.Error()
+			break
+		}
+		var changed []string
+		if parts[0] == "publish" {
+			setDate := confirmed(promptUser("Set the date of the post to now? [y/N] Default: N.\n> "))
+			changed, err = blog.Publish(post, setDate)
 		} else {
-			i, err := strconv.Atoi(strings.Trim(promptUser("Enter a post number to edit:\n> "), "\n\r "))
-			check(err)
-			index = i
-		}
-		if index > 0 && index < len(blog.Posts) {
-			startEditor(blog.Posts[index-1].Path)
-		}
-	case 'a':
-		title := ""
-		if len(parts) > 1 {
-			// Call creation of new post with title given
-			title = strings.Join(parts[1:], " ")
+			changed, err = blog.Unpublish(post)
+		}
+		if err != nil {
+			statusMessage = err.Error()
+		} else if len(changed) == 0 {
+			statusMessage = fmt.Sprintf("Nothing to change for '%s'", post.Title)
 		} else {
-			title = promptUser("Please enter a title for the post:\n> ")
-		}
-		fmt.Println("Attempting to create post with title: %s", title)
-		postPath := blog.NewPost(title, config.Extension)
-		startEditor(postPath)
-	case 'd':
-		// Ask for confirmation first
-		// Possibly: add a hugo 'trash' folder in future for soft deletion.
-		i, err := strconv.Atoi(strings.Trim(parts[1], "\n\r "))
-		check(err)
-		post := blog.Posts[i-1]
-		warn := "WARNING: You are about to delete the post titled '%s'.\nThis action is irreversible, especially if the post has not been synchronised with git.\nProceed? [y/N] Default: N.\n> "
-		ans := promptUser(fmt.Sprintf(warn, post.Title))
-		if ans[0] == 'y' {
-			blog.DeletePost(post.Path)
-		}
-	case 'n':
+			statusMessage = fmt.Sprintf("Updated %s of '%s'", strings.Join(changed, ", "), post.Title)
+		}
+	case "s", "sort":
+		if err := view.setSort(parts[1:]); err != nil {
+			statusMessage = err.Error()
+		}
+	case "f", "filter":
+		if err := view.setFilter(parts[1:]); err != nil {
+			statusMessage = err.Error()
+		}
+		currentPageIndex = 1
+	case "site", "sites":
+		if len(parts) == 1 {
+			fmt.Print(siteList())
+			promptUser("Press Enter to return to the post list")
+			break
+		}
+		i, err := findSite(strings.Join(parts[1:], " "))
+		if err != nil {
+			statusMessage = err.Error()
+			break
+		}
+		switched, err := switchSite(i)
+		if err != nil {
+			statusMessage = fmt.Sprintf("Could not open %s: %s", config.Sites[i].Name, err)
+			break
+		}
+		blog = switched
+		if statusMessage == "" {
+			statusMessage = fmt.Sprintf("Switched to %s", config.Sites[i].Name)
+		}
+	case "tax", "taxonomy":
+		listing, err := taxCommand(parts[1:], &blog)
+		if err != nil {
+			statusMessage = err.Error()
+		} else if listing != "" {
+			fmt.Print(listing)
+			promptUser("Press Enter to return to the post list")
+		}
+	case "trash":
+		listing, err := trashCommand(parts[1:], &blog)
+		if err != nil {
+			statusMessage = err.Error()
+		} else if listing != "" {
+			fmt.Print(listing)
+			promptUser("Press Enter to return to the post list")
+		}
+	case "sync":
+		fmt.Println("Synchronising with git...")
+		content, _ := filepath.Rel(blog.Path, blog.ContentDir())
+		result, err := git.Sync(blog.Path, content)
+		if err != nil {
+			statusMessage = fmt.Sprintf("Sync failed: %s", err)
+			break
+		}
+		statusMessage = syncSummary(result)
+		reloaded, err := loadSite(config.Sites[activeSite])
+		if err != nil {
+			statusMessage += fmt.Sprintf(", but reloading the site failed: %s", err)
+			break
+		}
+		blog = reloaded
+	case "deploy":
+		dryRun := len(parts) > 1 && (parts[1] == "--dry-run" || parts[1] == "dry")
+		listing, err := deploy(blog, config.Sites[activeSite].Deploy, dryRun)
+		if err != nil {
+			statusMessage = fmt.Sprintf("Deploy failed: %s", err)
+		} else if listing != "" {
+			fmt.Print(listing)
+			promptUser("Press Enter to return to the post list")
+		}
+	case "n", "next":
 		if currentPageIndex < numPages {
 			currentPageIndex++
 		}
-	case 'p':
+	case "p", "prev":
 		if currentPageIndex > 1 {
 			currentPageIndex--
 		}
-	case 'q':
+	case "preview":
+		listing, err := previewCommand(parts[1:], blog)
+		if err != nil {
+			statusMessage = err.Error()
+		} else if listing != "" {
+			fmt.Print(listing)
+			promptUser("Press Enter to return to the post list")
+		}
+	case "q", "quit":
+		saveIndex(blog)
 		clearTerm()
 		fmt.Println("You have slain the hydra...")
-		os.Exit(0)
+		exit(0)
 	}
 
 	return blog
 }
 
+// postGitState describes whether a post has been committed: new, modified or
+// clean.
+func postGitState(status git.RepoStatus, blog hugo.Blog, post hugo.Post) string {
+	file, changed := status.File(path.Join(blog.Path, post.Path))
+	switch {
+	case !changed:
+		return "clean"
+	case file.IsNew():
+		return "new"
+	}
+	return "modified"
+}
+
+// deploy builds the site and publishes it with git. For a dry run the files
+// that would change are returned for display instead, and the site is built
+// into a temporary directory so that the publish directory is left alone.
+func deploy(blog hugo.Blog, conf DeployConfig, dryRun bool) (string, error) {
+	fmt.Println("Building the site...")
+	source := blog.PublishDir()
+	if dryRun {
+		dir, err := ioutil.TempDir("", "hydra-build")
+		if err != nil {
+			return "", err
+		}
+		defer os.RemoveAll(dir)
+		source = dir
+	}
+	if err := blog.Build(source); err != nil {
+		return "", err
+	}
+
+	fmt.Println("Deploying...")
+	result, err := git.Deploy(blog.Path, git.DeployOptions{
+		Source:  source,
+		Remote:  conf.Remote,
+		Branch:  conf.Branch,
+		Message: conf.Message,
+		DryRun:  dryRun,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !dryRun {
+		if result.Pushed {
+			statusMessage = fmt.Sprintf("Deployed %d changed files", len(result.Files))
+		} else {
+			statusMessage = "The deployed site is already up to date"
+		}
+		return "", nil
+	}
+
+	var listing strings.Builder
+	fmt.Fprintf(&listing, "A deploy would change %d files:\n", len(result.Files))
+	for _, file := range result.Files {
+		state := "modified"
+		switch {
+		case file.IsNew():
+			state = "added"
+		case file.Staged == 'D':
+			state = "deleted"
+		}
+		fmt.Fprintf(&listing, "  %-9s %s\n", state, file.Path)
+	}
+	return listing.String(), nil
+}
+
+// syncSummary describes the result of a git sync in one line.
+func syncSummary(result git.SyncResult) string {
+	summary := "Nothing to commit"
+	if result.Committed {
+		summary = fmt.Sprintf("Committed %d added, %d modified and %d deleted files",
+			len(result.Added), len(result.Modified), len(result.Deleted))
+	}
+	if result.Pushed {
+		summary += ", pulled and pushed"
+	} else {
+		summary += " (no upstream branch to pull or push)"
+	}
+	return summary
+}
+
+// splitArgs splits a command into its arguments on spaces, keeping double
+// quoted arguments (such as multi-word terms) together.
+func splitArgs(cmd string) []string {
+	var args []string
+	var current strings.Builder
+	quoted, started := false, false
+	for _, c := range strings.TrimSpace(cmd) {
+		switch {
+		case c == '"':
+			quoted = !quoted
+			started = true
+		case (c == ' ' || c == '\t') && !quoted:
+			if started {
+				args = append(args, current.String())
+				current.Reset()
+				started = false
+			}
+		default:
+			current.WriteRune(c)
+			started = true
+		}
+	}
+	if started {
+		args = append(args, current.String())
+	}
+	return args
+}
+
+// promptPost returns the post whose number is given as the first argument of
+// a command, asking the user for one if it is missing.
+func promptPost(parts []string, prompt string, blog hugo.Blog) (hugo.Post, error) {
+	arg := ""
+	if len(parts) > 1 {
+		arg = parts[1]
+	} else {
+		arg = promptUser(prompt)
+	}
+	arg = strings.TrimSpace(arg)
+	i, err := strconv.Atoi(arg)
+	if err != nil {
+		return hugo.Post{}, fmt.Errorf("'%s' is not a post number", arg)
+	}
+	if i < 1 || i > len(blog.Posts) {
+		return hugo.Post{}, fmt.Errorf("%w: there is no post number %d", hugo.ErrPostNotFound, i)
+	}
+	return blog.Posts[i-1], nil
+}
+
+// promptUser prints the prompt and returns the line the user enters. If the
+// input has ended there is nothing left to do, so hydra quits.
 func promptUser(prompt string) string {
 	fmt.Print(prompt)
-	input := bufio.NewReader(os.Stdin)
-	ans, err := input.ReadString('\n')
-	check(err)
+	ans, err := stdin.ReadString('\n')
+	if err != nil && ans == "" {
+		fmt.Println()
+		exit(0)
+	}
 	return ans
 }
 
+// confirmed reports whether the answer to a [y/N] prompt was yes.
+func confirmed(ans string) bool {
+	ans = strings.TrimSpace(ans)
+	return ans != "" && (ans[0] == 'y' || ans[0] == 'Y')
+}
+
 func printPostList(blog hugo.Blog) {
 	// We might want to paginate the number of blog posts
 	// Currently we will set the max to 10 posts per page
 
 	header, list := genPostList(blog)
 
+	// Calculate the number of pages
+	numPages = int(math.Ceil(float64(len(list)) / float64(maxItemsPerPage)))
+	if currentPageIndex > numPages && numPages > 0 {
+		currentPageIndex = numPages
+	}
+
 	for _, col := range header {
 		fmt.Print(col + "\t")
 	}
@@ -216,8 +692,9 @@ func printPostList(blog hugo.Blog) {
 
 	}
 
-	fmt.Printf("Showing [%d-%d]", startPostIndex+1, endPostIndex)
+	fmt.Printf("Showing [%d-%d] of %d", startPostIndex+1, endPostIndex, len(list))
 	fmt.Printf(" | Page %d of %d\n", currentPageIndex, numPages)
+	fmt.Println(view)
 }
 
 func clearTerm() {
@@ -0,0 +1,217 @@
+package git
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DeployOptions control where Deploy publishes a built site.
+type DeployOptions struct {
+	// Source is the directory holding the built site, e.g. `public`.
+	Source string
+	// Remote is the repository to push to. It can be the name of a remote of
+	// the site repository (such as "origin"), a URL or a local path.
+	Remote string
+	// Branch receives the built site, e.g. "gh-pages".
+	Branch string
+	// Message is used for the commit. A default is generated when empty.
+	Message string
+	// DryRun stops after working out which files would change.
+	DryRun bool
+}
+
+// A DeployResult lists the files a deploy changed (or would change, for a dry
+// run) relative to the root of the deploy branch.
+type DeployResult struct {
+	Files     []FileStatus
+	Committed bool
+	Pushed    bool
+}
+
+// Deploy publishes the contents of opts.Source to a branch of a repository,
+// such as the gh-pages branch used by GitHub Pages. The branch is checked out
+// in a temporary work tree, replaced with the built site, committed and
+// pushed. dir is the site repository, used to resolve remote names and the
+// committer identity.
+func Deploy(dir string, opts DeployOptions) (DeployResult, error) {
+	result := DeployResult{}
+	if opts.Branch == "" {
+		opts.Branch = "gh-pages"
+	}
+	if opts.Remote == "" {
+		opts.Remote = "origin"
+	}
+	remote, err := resolveRemote(dir, opts.Remote)
+	if err != nil {
+		return result, err
+	}
+	opts.Remote = remote
+	if info, err := os.Stat(opts.Source); err != nil || !info.IsDir() {
+		return result, fmt.Errorf("nothing to deploy, %s is not a directory", opts.Source)
+	}
+
+	worktree, err := ioutil.TempDir("", "hydra-deploy")
+	if err != nil {
+		return result, err
+	}
+	defer os.RemoveAll(worktree)
+
+	if err := checkoutBranch(worktree, opts.Remote, opts.Branch); err != nil {
+		return result, err
+	}
+	if err := replaceContents(worktree, opts.Source); err != nil {
+		return result, err
+	}
+
+	if _, err := run(worktree, "add", "--all"); err != nil {
+		return result, err
+	}
+	status, err := Status(worktree)
+	if err != nil {
+		return result, err
+	}
+	result.Files = status.Files
+	if opts.DryRun || len(result.Files) == 0 {
+		return result, nil
+	}
+
+	message := opts.Message
+	if message == "" {
+		message = fmt.Sprintf("hydra deploy at %s", time.Now().Format("2006-01-02 15:04"))
+	}
+	args := append(identity(dir), "commit", "-m", message)
+	if _, err := run(worktree, args...); err != nil {
+		return result, err
+	}
+	result.Committed = true
+
+	if _, err := run(worktree, "push", "origin", "HEAD:refs/heads/"+opts.Branch); err != nil {
+		return result, err
+	}
+	result.Pushed = true
+	return result, nil
+}
+
+// resolveRemote turns the remote to deploy to into something git can use from
+// any directory. A remote of the site repository is replaced by its URL, and
+// local paths, which git resolves against the directory it runs in, are made
+// absolute by resolving them against dir.
+func resolveRemote(dir, remote string) (string, error) {
+	name := remote
+	if url, err := run(dir, "remote", "get-url", remote); err == nil {
+		remote = strings.TrimSpace(url)
+	}
+	if !isRelativePath(remote) {
+		return remote, nil
+	}
+	abs := filepath.Join(dir, remote)
+	if _, err := os.Stat(abs); err != nil {
+		return "", fmt.Errorf("%s is neither a remote of the site repository nor a local repository", name)
+	}
+	return abs, nil
+}
+
+// isRelativePath reports whether a remote is a relative local path rather
+// than an absolute path, a URL or an scp-like address such as
+// `git@github.com:user/site.git`.
+func isRelativePath(remote string) bool {
+	if strings.Contains(remote, "://") || filepath.IsAbs(remote) {
+		return false
+	}
+	colon := strings.Index(remote, ":")
+	return colon < 0 || strings.Contains(remote[:colon], "/")
+}
+
+// checkoutBranch clones the branch of the remote into worktree, or starts a
+// new orphan branch if the remote does not have it yet.
+func checkoutBranch(worktree, remote, branch string) error {
+	heads, err := run(worktree, "ls-remote", "--heads", remote, branch)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(heads) != "" {
+		_, err := run(worktree, "clone", "--quiet", "--depth", "1", "--single-branch", "--branch", branch, remote, ".")
+		return err
+	}
+
+	steps := [][]string{
+		{"init", "--quiet"},
+		{"checkout", "--quiet", "--orphan", branch},
+		{"remote", "add", "origin", remote},
+	}
+	for _, step := range steps {
+		if _, err := run(worktree, step...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// identity passes the committer configured for the site repository on to git
+// commands run elsewhere.
+func identity(dir string) []string {
+	var args []string
+	for _, key := range []string{"user.name", "user.email"} {
+		if value, err := run(dir, "config", key); err == nil && strings.TrimSpace(value) != "" {
+			args = append(args, "-c", key+"="+strings.TrimSpace(value))
+		}
+	}
+	return args
+}
+
+// replaceContents removes everything in worktree except the .git directory and
+// copies the contents of source into it.
+func replaceContents(worktree, source string) error {
+	entries, err := ioutil.ReadDir(worktree)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Name() == ".git" {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(worktree, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	return filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		target := filepath.Join(worktree, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		}
+		return copyFile(path, target, info.Mode())
+	})
+}
+
+func copyFile(from, to string, mode os.FileMode) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
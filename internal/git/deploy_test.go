@@ -0,0 +1,162 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// newDeploySetup creates a site repository and, next to it, a bare repository
+// that the site uses as the remote "pages", given by a relative path.
+func newDeploySetup(t *testing.T) (site, remote string) {
+	t.Helper()
+	site = newRepo(t)
+	remote = filepath.Join(filepath.Dir(site), filepath.Base(site)+"-pages.git")
+	if err := os.Mkdir(remote, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(remote) })
+	gitRun(t, remote, "init", "--quiet", "--bare")
+	gitRun(t, site, "remote", "add", "pages", "../"+filepath.Base(remote))
+	return site, remote
+}
+
+// deployedFiles lists the files on a branch of a bare repository.
+func deployedFiles(t *testing.T, remote, branch string) []string {
+	t.Helper()
+	out := gitRun(t, remote, "ls-tree", "-r", "--name-only", "-z", branch)
+	files := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	sort.Strings(files)
+	return files
+}
+
+func changedFiles(result DeployResult) []string {
+	var files []string
+	for _, file := range result.Files {
+		files = append(files, file.Path)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func TestDeploy(t *testing.T) {
+	site, remote := newDeploySetup(t)
+	public := filepath.Join(site, "public")
+	writeFile(t, public, "index.html", "home")
+	writeFile(t, public, "posts/a/index.html", "a")
+	writeFile(t, public, ".git/HEAD", "not copied")
+
+	result, err := Deploy(site, DeployOptions{Source: public, Remote: "pages", Message: "first"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Committed || !result.Pushed {
+		t.Errorf("the first deploy was not committed and pushed: %+v", result)
+	}
+	want := []string{"index.html", "posts/a/index.html"}
+	if got := deployedFiles(t, remote, "gh-pages"); !reflect.DeepEqual(got, want) {
+		t.Errorf("deployed %v, want %v", got, want)
+	}
+	if message := gitRun(t, remote, "log", "-1", "--format=%s", "gh-pages"); message != "first\n" {
+		t.Errorf("the commit message is %q", message)
+	}
+
+	// A second deploy updates the branch with only the changes.
+	writeFile(t, public, "index.html", "new home")
+	writeFile(t, public, "posts/b/index.html", "b")
+	if err := os.RemoveAll(filepath.Join(public, "posts", "a")); err != nil {
+		t.Fatal(err)
+	}
+	result, err = Deploy(site, DeployOptions{Source: public, Remote: "pages"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"index.html", "posts/a/index.html", "posts/b/index.html"}
+	if got := changedFiles(result); !reflect.DeepEqual(got, want) {
+		t.Errorf("changed %v, want %v", got, want)
+	}
+	want = []string{"index.html", "posts/b/index.html"}
+	if got := deployedFiles(t, remote, "gh-pages"); !reflect.DeepEqual(got, want) {
+		t.Errorf("deployed %v, want %v", got, want)
+	}
+	if commits := gitRun(t, remote, "rev-list", "--count", "gh-pages"); commits != "2\n" {
+		t.Errorf("gh-pages has %s commits, want 2", strings.TrimSpace(commits))
+	}
+
+	// Nothing is committed when the site did not change.
+	result, err = Deploy(site, DeployOptions{Source: public, Remote: "pages"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Committed || len(result.Files) != 0 {
+		t.Errorf("an unchanged site was deployed: %+v", result)
+	}
+}
+
+func TestDeployDryRun(t *testing.T) {
+	site, remote := newDeploySetup(t)
+	public := filepath.Join(site, "public")
+	writeFile(t, public, "index.html", "home")
+	if _, err := Deploy(site, DeployOptions{Source: public, Remote: "pages", Branch: "site"}); err != nil {
+		t.Fatal(err)
+	}
+	head := gitRun(t, remote, "rev-parse", "site")
+
+	writeFile(t, public, "about.html", "about")
+	result, err := Deploy(site, DeployOptions{Source: public, Remote: "pages", Branch: "site", DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Committed || result.Pushed {
+		t.Errorf("a dry run committed or pushed: %+v", result)
+	}
+	if got := changedFiles(result); !reflect.DeepEqual(got, []string{"about.html"}) {
+		t.Errorf("a dry run lists %v, want [about.html]", got)
+	}
+	if after := gitRun(t, remote, "rev-parse", "site"); after != head {
+		t.Error("a dry run changed the remote branch")
+	}
+}
+
+func TestDeployRelativePath(t *testing.T) {
+	site, remote := newDeploySetup(t)
+	public := filepath.Join(site, "public")
+	writeFile(t, public, "index.html", "home")
+
+	relative := "../" + filepath.Base(remote)
+	if _, err := Deploy(site, DeployOptions{Source: public, Remote: relative}); err != nil {
+		t.Fatal(err)
+	}
+	if got := deployedFiles(t, remote, "gh-pages"); !reflect.DeepEqual(got, []string{"index.html"}) {
+		t.Errorf("deployed %v, want [index.html]", got)
+	}
+}
+
+func TestDeployUnknownRemote(t *testing.T) {
+	site := newRepo(t)
+	public := filepath.Join(site, "public")
+	writeFile(t, public, "index.html", "home")
+	if _, err := Deploy(site, DeployOptions{Source: public}); err == nil {
+		t.Error("deploying to a missing origin remote did not fail")
+	}
+}
+
+func TestIsRelativePath(t *testing.T) {
+	for remote, want := range map[string]bool{
+		"../pages.git":                     true,
+		"pages.git":                        true,
+		"sub/dir:colon":                    true,
+		"/srv/pages.git":                   false,
+		"https://github.com/u/u.github.io": false,
+		"file:///srv/pages.git":            false,
+		"git@github.com:u/u.github.io.git": false,
+		"host:pages.git":                   false,
+	} {
+		if got := isRelativePath(remote); got != want {
+			t.Errorf("isRelativePath(%q) = %v, want %v", remote, got, want)
+		}
+	}
+}
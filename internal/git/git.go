@@ -1,69 +1,357 @@
 package git
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
-	"log"
 	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// ErrNotARepo is returned when a directory is not inside a git work tree.
+var ErrNotARepo = errors.New("not a git repository")
+
+// ErrGitNotFound is returned when the git executable is not installed or not
+// in $PATH.
+var ErrGitNotFound = errors.New("git was not found, is it installed and in your $PATH?")
+
+// A ConflictError is returned by Sync when pulling the remote changes causes
+// conflicts. The local commit is kept and the rebase is aborted, so the repo is
+// left as it was before the pull.
+type ConflictError struct {
+	Files []string
+}
+
+func (e ConflictError) Error() string {
+	return fmt.Sprintf("pulling from the remote caused conflicts in: %s", strings.Join(e.Files, ", "))
+}
+
+// A FileStatus is the state of one changed file, as reported by
+// `git status --porcelain=v2`. Staged and Unstaged hold the status letters for
+// the index and the work tree ('M', 'A', 'D', 'R', 'C', 'U' or '.' when
+// unchanged). Untracked files have both set to '?'.
+type FileStatus struct {
+	Path, OrigPath   string
+	Staged, Unstaged byte
+}
+
+// IsStaged reports whether the file has changes in the index.
+func (f FileStatus) IsStaged() bool {
+	return f.Staged != '.' && f.Staged != '?'
+}
+
+// IsUnstaged reports whether the file has changes in the work tree that are
+// not in the index.
+func (f FileStatus) IsUnstaged() bool {
+	return f.Unstaged != '.' && f.Unstaged != '?'
+}
+
+// IsUntracked reports whether git does not know about the file yet.
+func (f FileStatus) IsUntracked() bool {
+	return f.Staged == '?'
+}
+
+// IsNew reports whether the file has never been committed.
+func (f FileStatus) IsNew() bool {
+	return f.IsUntracked() || f.Staged == 'A'
+}
+
+// RepoStatus summarises the state of a repository. File paths are relative to
+// Root, the top level of the work tree.
 type RepoStatus struct {
-	ModifiedFiles  []string
-	UntrackedFiles []string
-	CanPull        bool
-	CanPush        bool
+	Root             string
+	Branch, Upstream string
+	Ahead, Behind    int
+	Files            []FileStatus
+
+	ModifiedFiles   []string
+	UntrackedFiles  []string
+	DeletedFiles    []string
+	ConflictedFiles []string
+	CanPull         bool
+	CanPush         bool
 }
 
-// Checks whether or not the path is a git repo
-func IsRepo() bool {
-	cmd := exec.Command("git", "rev-parse", "--is-inside-work-tree")
-	_, err := cmd.Output()
-	if err == nil {
-		return true
-	} else {
-		return false
+// File looks up the status of a file by its absolute path. Files without
+// changes are not found.
+func (status RepoStatus) File(path string) (FileStatus, bool) {
+	rel, err := filepath.Rel(resolve(status.Root), resolve(path))
+	if err != nil {
+		return FileStatus{}, false
 	}
+	rel = filepath.ToSlash(rel)
+	for _, file := range status.Files {
+		if file.Path == rel {
+			return file, true
+		}
+	}
+	return FileStatus{}, false
+}
+
+func resolve(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return filepath.Clean(path)
+}
+
+// A SyncResult describes what Sync did.
+type SyncResult struct {
+	Added, Modified, Deleted []string
+	Committed                bool
+	Pulled, Pushed           bool
+}
+
+// run executes git in dir and returns its output. Errors include whatever git
+// printed to stderr.
+func run(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if errors.Is(err, exec.ErrNotFound) {
+		return "", ErrGitNotFound
+	}
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return string(out), fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return string(out), nil
+}
+
+// Checks whether or not the path is a git repo
+func IsRepo(dir string) bool {
+	_, err := run(dir, "rev-parse", "--is-inside-work-tree")
+	return err == nil
+}
+
+// hasUpstream reports whether the current branch tracks a remote branch.
+func hasUpstream(dir string) bool {
+	_, err := run(dir, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}")
+	return err == nil
 }
 
 // Checks for changes in the remote git
 // Process includes:
 // 1. fetching remote repos
 // 2. updating local repo with remote changes
-func Update() {
-	cmd := exec.Command("git", "fetch")
-	_, err := cmd.Output()
-	if err != nil {
-		panic(err)
+func Update(dir string) error {
+	if !IsRepo(dir) {
+		return ErrNotARepo
+	}
+	if !hasUpstream(dir) {
+		return nil
+	}
+	if _, err := run(dir, "fetch"); err != nil {
+		return err
 	}
+	return pull(dir)
+}
 
-	cmd = exec.Command("git", "status", "--porcelain")
-	_, err = cmd.Output()
+// pull rebases local commits onto the upstream branch. If that causes conflicts
+// the rebase is aborted and a ConflictError is returned.
+func pull(dir string) error {
+	_, err := run(dir, "pull", "--rebase")
+	if err == nil {
+		return nil
+	}
+	out, _ := run(dir, "diff", "--name-only", "--diff-filter=U", "-z")
+	var conflicts []string
+	for _, file := range strings.Split(out, "\x00") {
+		if file != "" {
+			conflicts = append(conflicts, file)
+		}
+	}
+	if len(conflicts) == 0 {
+		return err
+	}
+	run(dir, "rebase", "--abort")
+	return ConflictError{Files: conflicts}
+}
+
+// Status lists all of the files that have been added (untracked), modified,
+// deleted or renamed, both staged and unstaged, and how far the branch is
+// ahead of or behind its upstream.
+func Status(dir string) (RepoStatus, error) {
+	if !IsRepo(dir) {
+		return RepoStatus{}, ErrNotARepo
+	}
+	root, err := run(dir, "rev-parse", "--show-toplevel")
 	if err != nil {
-		panic(err)
+		return RepoStatus{}, err
+	}
+	out, err := run(dir, "status", "--porcelain=v2", "--branch", "-z", "--untracked-files=all")
+	if err != nil {
+		return RepoStatus{}, err
+	}
+	status, err := parseStatus(out)
+	status.Root = strings.TrimSpace(root)
+	return status, err
+}
+
+// parseStatus parses the output of `git status --porcelain=v2 --branch -z`.
+func parseStatus(out string) (RepoStatus, error) {
+	status := RepoStatus{}
+	entries := strings.Split(out, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if entry == "" {
+			continue
+		}
+		switch entry[0] {
+		case '#':
+			fields := strings.Fields(entry)
+			if len(fields) < 3 {
+				continue
+			}
+			switch fields[1] {
+			case "branch.head":
+				status.Branch = fields[2]
+			case "branch.upstream":
+				status.Upstream = fields[2]
+			case "branch.ab":
+				if len(fields) < 4 {
+					return status, fmt.Errorf("unexpected git status line: %s", entry)
+				}
+				status.Ahead, _ = strconv.Atoi(strings.TrimPrefix(fields[2], "+"))
+				status.Behind, _ = strconv.Atoi(strings.TrimPrefix(fields[3], "-"))
+			}
+		case '1', '2', 'u':
+			// Ordinary, renamed/copied and unmerged entries. The path is the
+			// last field, and renames are followed by the original path.
+			fieldCount := map[byte]int{'1': 9, '2': 10, 'u': 11}[entry[0]]
+			fields := strings.SplitN(entry, " ", fieldCount)
+			if len(fields) < fieldCount || len(fields[1]) != 2 {
+				return status, fmt.Errorf("unexpected git status line: %s", entry)
+			}
+			file := FileStatus{
+				Path:     fields[fieldCount-1],
+				Staged:   fields[1][0],
+				Unstaged: fields[1][1],
+			}
+			if entry[0] == '2' && i+1 < len(entries) {
+				i++
+				file.OrigPath = entries[i]
+			}
+			status.add(file, entry[0] == 'u')
+		case '?':
+			status.add(FileStatus{Path: entry[2:], Staged: '?', Unstaged: '?'}, false)
+		}
 	}
+	status.CanPull = status.Behind > 0
+	status.CanPush = status.Ahead > 0
+	return status, nil
+}
 
+func (status *RepoStatus) add(file FileStatus, conflicted bool) {
+	status.Files = append(status.Files, file)
+	switch {
+	case conflicted:
+		status.ConflictedFiles = append(status.ConflictedFiles, file.Path)
+	case file.IsUntracked():
+		status.UntrackedFiles = append(status.UntrackedFiles, file.Path)
+	case file.Staged == 'D' || file.Unstaged == 'D':
+		status.DeletedFiles = append(status.DeletedFiles, file.Path)
+	default:
+		status.ModifiedFiles = append(status.ModifiedFiles, file.Path)
+	}
 }
 
-// Get a list of all of the files that have been added (untracked) or modified
-// as well as if there are any pulls that can be made
-// Returns a RepoStatus struct
-func Status() string {
-	cmd := exec.Command("git", "status", "--porcelain")
-	result, err := cmd.Output()
+// Sync stages all changes below the given paths (relative to dir), commits
+// them with a message listing the added, modified and deleted files, rebases
+// onto the remote and pushes. Pulling and pushing are skipped when the branch
+// has no upstream.
+func Sync(dir string, paths ...string) (SyncResult, error) {
+	result := SyncResult{}
+	if !IsRepo(dir) {
+		return result, ErrNotARepo
+	}
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	args := append([]string{"add", "--all", "--"}, paths...)
+	if _, err := run(dir, args...); err != nil {
+		return result, err
+	}
+
+	args = append([]string{"diff", "--cached", "--name-status", "--no-renames", "-z", "--"}, paths...)
+	staged, err := run(dir, args...)
 	if err != nil {
-		panic(err)
+		return result, err
 	}
-	return string(result)
+	parseNameStatus(staged, &result)
+
+	if len(result.Added)+len(result.Modified)+len(result.Deleted) > 0 {
+		args = append([]string{"commit", "-m", syncMessage(result), "--"}, paths...)
+		if _, err := run(dir, args...); err != nil {
+			return result, err
+		}
+		result.Committed = true
+	}
+
+	if !hasUpstream(dir) {
+		return result, nil
+	}
+	if err := pull(dir); err != nil {
+		return result, err
+	}
+	result.Pulled = true
+	if _, err := run(dir, "push"); err != nil {
+		return result, err
+	}
+	result.Pushed = true
+	return result, nil
 }
 
-func Sync() {
-	status := Status()
-	if len(status) > 0 {
-		//cmd := exec.Command("git", "add", "--all")
-		msg := fmt.Sprintf("hydra sync at %d\n\n%s", time.Now().Unix(), status)
-		log.Println(msg)
+// parseNameStatus sorts the files listed by `git diff --name-status -z` into
+// the added, modified and deleted files of result. With -z the paths are
+// given as they are instead of quoted, and each status and path ends with a
+// NUL.
+func parseNameStatus(out string, result *SyncResult) {
+	entries := strings.Split(out, "\x00")
+	for i := 0; i+1 < len(entries); i += 2 {
+		status, file := entries[i], entries[i+1]
+		if status == "" || file == "" {
+			continue
+		}
+		switch status[0] {
+		case 'A':
+			result.Added = append(result.Added, file)
+		case 'D':
+			result.Deleted = append(result.Deleted, file)
+		default:
+			result.Modified = append(result.Modified, file)
+		}
 	}
-	// if len(status.ModifiedFiles) > 0 || len(status.UntrackedFiles) > 0 {
-	// }
+}
 
+func syncMessage(result SyncResult) string {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "hydra sync at %s\n", time.Now().Format("2006-01-02 15:04"))
+	sections := []struct {
+		name  string
+		files []string
+	}{
+		{"Added", result.Added},
+		{"Modified", result.Modified},
+		{"Deleted", result.Deleted},
+	}
+	for _, section := range sections {
+		if len(section.files) == 0 {
+			continue
+		}
+		fmt.Fprintf(&msg, "\n%s:\n", section.name)
+		for _, file := range section.files {
+			fmt.Fprintf(&msg, "  %s\n", file)
+		}
+	}
+	return msg.String()
 }
@@ -0,0 +1,179 @@
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newRepo creates a git repository in a temporary directory, skipping the test
+// if git is not installed.
+func newRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	gitRun(t, dir, "init", "--quiet")
+	gitRun(t, dir, "config", "user.name", "Test")
+	gitRun(t, dir, "config", "user.email", "test@example.com")
+	gitRun(t, dir, "config", "commit.gpgsign", "false")
+	return dir
+}
+
+func gitRun(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := run(dir, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	file := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseNameStatus(t *testing.T) {
+	var result SyncResult
+	parseNameStatus("A\x00content/é.md\x00M\x00content/with space.md\x00D\x00content/old\tname.md\x00", &result)
+	want := SyncResult{
+		Added:    []string{"content/é.md"},
+		Modified: []string{"content/with space.md"},
+		Deleted:  []string{"content/old\tname.md"},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("got %#v, want %#v", result, want)
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	const hash = "0123456789012345678901234567890123456789"
+	tests := []struct {
+		name string
+		out  string
+		want RepoStatus
+	}{
+		{
+			name: "branch",
+			out:  "# branch.oid " + hash + "\x00# branch.head main\x00# branch.upstream origin/main\x00# branch.ab +2 -1\x00",
+			want: RepoStatus{Branch: "main", Upstream: "origin/main", Ahead: 2, Behind: 1, CanPull: true, CanPush: true},
+		},
+		{
+			name: "ordinary",
+			out:  "1 .M N... 100644 100644 100644 " + hash + " " + hash + " content/with space.md\x00",
+			want: RepoStatus{
+				Files:         []FileStatus{{Path: "content/with space.md", Staged: '.', Unstaged: 'M'}},
+				ModifiedFiles: []string{"content/with space.md"},
+			},
+		},
+		{
+			name: "deleted",
+			out:  "1 D. N... 100644 000000 000000 " + hash + " " + hash + " content/gone.md\x00",
+			want: RepoStatus{
+				Files:        []FileStatus{{Path: "content/gone.md", Staged: 'D', Unstaged: '.'}},
+				DeletedFiles: []string{"content/gone.md"},
+			},
+		},
+		{
+			name: "renamed",
+			out: "2 R. N... 100644 100644 100644 " + hash + " " + hash + " R100 content/new name.md\x00content/old name.md\x00" +
+				"1 A. N... 000000 100644 100644 " + hash + " " + hash + " content/added.md\x00",
+			want: RepoStatus{
+				Files: []FileStatus{
+					{Path: "content/new name.md", OrigPath: "content/old name.md", Staged: 'R', Unstaged: '.'},
+					{Path: "content/added.md", Staged: 'A', Unstaged: '.'},
+				},
+				ModifiedFiles: []string{"content/new name.md", "content/added.md"},
+			},
+		},
+		{
+			name: "unmerged",
+			out:  "u UU N... 100644 100644 100644 100644 " + hash + " " + hash + " " + hash + " content/both changed.md\x00",
+			want: RepoStatus{
+				Files:           []FileStatus{{Path: "content/both changed.md", Staged: 'U', Unstaged: 'U'}},
+				ConflictedFiles: []string{"content/both changed.md"},
+			},
+		},
+		{
+			name: "untracked",
+			out:  "? content/new post.md\x00? content/é.md\x00",
+			want: RepoStatus{
+				Files: []FileStatus{
+					{Path: "content/new post.md", Staged: '?', Unstaged: '?'},
+					{Path: "content/é.md", Staged: '?', Unstaged: '?'},
+				},
+				UntrackedFiles: []string{"content/new post.md", "content/é.md"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseStatus(test.out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got  %+v\nwant %+v", got, test.want)
+			}
+		})
+	}
+
+	for _, out := range []string{
+		"1 .M N... 100644 100644 100644 " + hash + " content/short.md\x00",
+		"u UU N... 100644 100644 100644 100644 " + hash + " content/short.md\x00",
+		"# branch.ab +2\x00",
+	} {
+		if _, err := parseStatus(out); err == nil {
+			t.Errorf("%q: expected an error", out)
+		}
+	}
+}
+
+func TestSyncListsUnquotedPaths(t *testing.T) {
+	dir := newRepo(t)
+	writeFile(t, dir, "content/old.md", "old")
+	writeFile(t, dir, "content/kept.md", "kept")
+	writeFile(t, dir, "notes.txt", "not synced")
+	gitRun(t, dir, "add", "content")
+	gitRun(t, dir, "commit", "--quiet", "-m", "initial")
+
+	writeFile(t, dir, "content/é.md", "new")
+	writeFile(t, dir, "content/kept.md", "changed")
+	if err := os.Remove(filepath.Join(dir, "content", "old.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Sync(dir, "content")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := SyncResult{
+		Added:     []string{"content/é.md"},
+		Modified:  []string{"content/kept.md"},
+		Deleted:   []string{"content/old.md"},
+		Committed: true,
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("got %#v, want %#v", result, want)
+	}
+
+	message := gitRun(t, dir, "log", "-1", "--format=%B")
+	if !strings.Contains(message, "  content/é.md\n") {
+		t.Errorf("the commit message does not list content/é.md as it is:\n%s", message)
+	}
+	if status := gitRun(t, dir, "status", "--porcelain", "--untracked-files=all"); status != "?? notes.txt\n" {
+		t.Errorf("files outside content were touched:\n%s", status)
+	}
+}
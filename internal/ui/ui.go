@@ -4,7 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"github.com/gdamore/tcell/v2"
-	"os"
+	"sort"
+	"sync"
 )
 
 // Mode defines the behaviour of the UI: Navigate or Input. It controls how
@@ -26,6 +27,17 @@ type CommandKey struct {
 	Mod  tcell.ModMask
 }
 
+// name returns a printable name for the key, e.g. "a" or "Enter".
+func (key CommandKey) name() string {
+	if key.Key == tcell.KeyRune {
+		return string(key.Rune)
+	}
+	if name, ok := tcell.KeyNames[key.Key]; ok {
+		return name
+	}
+	return fmt.Sprintf("Key[%d]", key.Key)
+}
+
 // Command stores a callback function and a description of the command for the
 // footer.
 type Command struct {
@@ -49,23 +61,22 @@ type PneumaUI struct {
 	Content     []Drawable
 	Commands    map[CommandKey]Command
 	suspended   bool
-}
 
-func check(e error) {
-	if e != nil {
-		panic(e)
-	}
+	// lock guards the screen and posted, which are used by Post from other
+	// goroutines.
+	lock   *sync.Mutex
+	posted []func()
 }
 
 // Init creates everything necessary for an interactive user-interface by
 // creating and initialising a new tcell.Screen, blank command map and setting
-// the cursor to 0,0. It returns a PneumaUI strict.
-func Init() PneumaUI {
-	screen, err := tcell.NewScreen()
-	check(err)
-	err = screen.Init()
-	check(err)
-	screen.Clear()
+// the cursor to 0,0. It returns a PneumaUI struct, or an error if the terminal
+// cannot be used.
+func Init() (PneumaUI, error) {
+	screen, err := newScreen()
+	if err != nil {
+		return PneumaUI{}, err
+	}
 	commands := make(map[CommandKey]Command)
 	ui := PneumaUI{
 		Screen:    screen,
@@ -75,8 +86,21 @@ func Init() PneumaUI {
 		Style:     tcell.StyleDefault,
 		Commands:  commands,
 		suspended: false,
+		lock:      &sync.Mutex{},
 	}
-	return ui
+	return ui, nil
+}
+
+func newScreen() (tcell.Screen, error) {
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		return nil, err
+	}
+	if err := screen.Init(); err != nil {
+		return nil, err
+	}
+	screen.Clear()
+	return screen, nil
 }
 
 // Redraw clears the screen and re-renders all of the widgets in content. It
@@ -98,12 +122,12 @@ func (ui *PneumaUI) Reset() {
 	ui.Content = make([]Drawable, 0)
 }
 
-// Close finalises the tcell.Screen and exits the program with status code 0.
-func (ui PneumaUI) Close() {
+// Close finalises the tcell.Screen and sets Exit, after which Tick does
+// nothing. The program decides how to exit, so it can clean up first.
+func (ui *PneumaUI) Close() {
 	ui.Screen.Clear()
 	ui.Screen.Sync()
 	ui.Screen.Fini()
-	os.Exit(0)
 	ui.Exit = true
 }
 
@@ -120,6 +144,19 @@ func (ui *PneumaUI) WaitForInput() string {
 	return ui.InputBuffer
 }
 
+// Prompt shows a prompt on the line above the footer and waits for the user
+// to enter a string, which is returned. Escape cancels the prompt and returns
+// an empty string.
+func (ui *PneumaUI) Prompt(prompt string) string {
+	_, h := ui.Screen.Size()
+	ui.Cursor = cursor{0, h - 3}
+	ui.putString(prompt)
+	ui.InputBuffer = ""
+	input := ui.WaitForInput()
+	ui.InputBuffer = ""
+	return input
+}
+
 // Confirm stalls user interaction with a prompt and waits for the user to
 // either accept or reject the prompt (default: reject). This then returns the
 // choice as a boolean.
@@ -156,6 +193,35 @@ func (ui *PneumaUI) Confirm(prompt string) bool {
 	return confirmed
 }
 
+// Post asks the goroutine calling Tick to run callback, for example to show
+// data that changed in the background, and to redraw the screen afterwards. It
+// can be called from any goroutine. Callbacks wait while the user is entering
+// text or the UI is suspended.
+func (ui *PneumaUI) Post(callback func()) {
+	ui.lock.Lock()
+	defer ui.lock.Unlock()
+	ui.posted = append(ui.posted, callback)
+	if !ui.suspended {
+		// Wake up PollEvent in Tick.
+		ui.Screen.PostEvent(tcell.NewEventInterrupt(nil))
+	}
+}
+
+// runPosted runs the callbacks given to Post since the last call.
+func (ui *PneumaUI) runPosted() {
+	ui.lock.Lock()
+	posted := ui.posted
+	ui.posted = nil
+	ui.lock.Unlock()
+	if len(posted) == 0 {
+		return
+	}
+	for _, callback := range posted {
+		callback()
+	}
+	ui.Redraw()
+}
+
 // SetCommands takes a map of CommandKeys and callback functions that will be
 // checked against EventKeys in Tick().
 func (ui *PneumaUI) SetCommands(commands map[CommandKey]Command) {
@@ -168,9 +234,12 @@ func (ui *PneumaUI) SetCommands(commands map[CommandKey]Command) {
 // alphanumerical letters to the input buffer (terminating on escape or enter)
 // and rendering the character to screen at the cursor.
 func (ui *PneumaUI) Tick() {
-	if ui.suspended {
+	if ui.suspended || ui.Exit {
 		return
 	}
+	if ui.Mode == Navigate {
+		ui.runPosted()
+	}
 
 	ui.drawFooter()
 	ui.Screen.Sync()
@@ -182,6 +251,9 @@ func (ui *PneumaUI) Tick() {
 			key := CommandKey{Key: ev.Key(), Rune: ev.Rune(), Mod: ev.Modifiers()}
 			if cmd, ok := ui.Commands[key]; ok {
 				cmd.Callback()
+				if ui.Exit {
+					return
+				}
 				ui.Redraw()
 			}
 		} else if ui.Mode == Input {
@@ -236,8 +308,16 @@ func (ui PneumaUI) drawFooter() {
 	var footerContent string
 	switch ui.Mode {
 	case Navigate:
-		for key, cmd := range ui.Commands {
-			footerContent += fmt.Sprintf("[%s]: %s ", string(key.Rune), cmd.Description)
+		// Sort the commands so the footer does not change between ticks.
+		keys := make([]CommandKey, 0, len(ui.Commands))
+		for key := range ui.Commands {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].name() < keys[j].name()
+		})
+		for _, key := range keys {
+			footerContent += fmt.Sprintf("[%s]: %s ", key.name(), ui.Commands[key].Description)
 		}
 	case Input:
 		footerContent = "INPUT"
@@ -315,22 +395,33 @@ func (ui *PneumaUI) AddTable(x, y int, headings []string, content [][]string) *T
 	return table
 }
 
+// AddPane appends a new Pane widget to the content.
+func (ui *PneumaUI) AddPane(x, y, width, height int, title string) *Pane {
+	pane := &Pane{X: x, Y: y, Width: width, Height: height, Title: title}
+	ui.Content = append(ui.Content, pane)
+	return pane
+}
+
 // Suspend stops and destroys the screen and allows another program to run
 func (ui *PneumaUI) Suspend() {
+	ui.lock.Lock()
+	defer ui.lock.Unlock()
 	ui.Screen.Fini()
 	ui.suspended = true
 }
 
 // Resume creates a new screen after the previous one was destroyed with Suspend
-func (ui *PneumaUI) Resume() {
+func (ui *PneumaUI) Resume() error {
 	if ui.suspended {
-		screen, err := tcell.NewScreen()
-		check(err)
-		err = screen.Init()
-		check(err)
-		screen.Clear()
+		screen, err := newScreen()
+		if err != nil {
+			return err
+		}
+		ui.lock.Lock()
 		ui.Screen = screen
 		ui.suspended = false
+		ui.lock.Unlock()
 		ui.Redraw()
 	}
+	return nil
 }
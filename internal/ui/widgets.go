@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"strings"
 )
 
 // A Drawable is any struct (typically) that can be drawn to a PneumaUI. It
@@ -20,13 +21,27 @@ type Label struct {
 // A Table is a structured widget that contains string data in rows and
 // columns. It does not manage headers or support non-string content, merely
 // renders it. The Index represents the row (0 indexed) that is currently
-// highlighted.
+// highlighted. If Height is set, at most that many rows are shown and the
+// table scrolls to keep the highlighted row visible.
 type Table struct {
 	X, Y     int
 	Headings []string
 	Content  [][]string
 	Active   bool
 	Index    int
+	Height   int
+	offset   int
+}
+
+// A Pane is a box with a title that shows the last lines of some text, such as
+// the log of a program. Lines that do not fit in the width of the pane are
+// cut off. A hidden pane is not drawn.
+type Pane struct {
+	X, Y          int
+	Width, Height int
+	Title         string
+	Lines         []string
+	Hidden        bool
 }
 
 // Draw renders a label to the given PneumaUI.
@@ -45,15 +60,15 @@ func (t *Table) AddRow(row []string) {
 // This does not trigger a redraw, but the changes will be seen upon the Draw()
 // function being called.
 func (t *Table) SetContent(headings []string, content [][]string) {
-    t.Headings = headings
-    t.Content = content
+	t.Headings = headings
+	t.Content = content
 }
 
 // Draw renders a table to the given PneumaUI. It makes sure to size the
 // columns to the max width of the widest item and does not truncate the
 // contents.  Furthermore, the selected item is higlighted with
 // tcell.Style.Reverse
-func (t Table) Draw(ui *PneumaUI) {
+func (t *Table) Draw(ui *PneumaUI) {
 	if !t.Active {
 		ui.Style = ui.Style.Dim(true)
 	}
@@ -74,7 +89,8 @@ func (t Table) Draw(ui *PneumaUI) {
 		maxWidth += width
 	}
 
-	ui.box(t.X, t.Y, maxWidth+1, len(t.Content)+2)
+	rows := t.visibleRows()
+	ui.box(t.X, t.Y, maxWidth+1, len(rows)+2)
 
 	ui.Style = ui.Style.Bold(true)
 	ui.Style = ui.Style.Underline(true)
@@ -88,9 +104,9 @@ func (t Table) Draw(ui *PneumaUI) {
 	ui.Style = ui.Style.Underline(false)
 
 	ui.MoveCursor(t.X+1, t.Y+2)
-	for i, row := range t.Content {
+	for i, row := range rows {
 		for col, item := range row {
-			if i == t.Index {
+			if i+t.offset == t.Index {
 				ui.Style = ui.Style.Reverse(true)
 				ui.putString(fmt.Sprintf("%-*s", colWidths[col], item))
 				ui.Style = ui.Style.Reverse(false)
@@ -105,15 +121,68 @@ func (t Table) Draw(ui *PneumaUI) {
 
 }
 
+// Draw renders the title and as many of the last lines of a pane as fit.
+func (p *Pane) Draw(ui *PneumaUI) {
+	if p.Hidden || p.Width < 3 || p.Height < 3 {
+		return
+	}
+	ui.box(p.X, p.Y, p.Width-1, p.Height-1)
+	ui.MoveCursor(p.X+2, p.Y)
+	ui.putString(fit(" "+p.Title+" ", p.Width-4))
+
+	lines := p.Lines
+	if len(lines) > p.Height-2 {
+		lines = lines[len(lines)-(p.Height-2):]
+	}
+	for i, line := range lines {
+		ui.MoveCursor(p.X+1, p.Y+1+i)
+		ui.putString(fit(line, p.Width-2))
+	}
+}
+
+// fit cuts text to at most width characters, and replaces tabs so that every
+// character takes up one cell.
+func fit(text string, width int) string {
+	runes := []rune(strings.ReplaceAll(text, "\t", "    "))
+	if len(runes) > width {
+		runes = runes[:width]
+	}
+	return string(runes)
+}
+
+// visibleRows returns the rows that fit in the height of the table, scrolling
+// so that the highlighted row is one of them.
+func (t *Table) visibleRows() [][]string {
+	if t.Height <= 0 || len(t.Content) <= t.Height {
+		t.offset = 0
+		return t.Content
+	}
+	if t.Index < t.offset {
+		t.offset = t.Index
+	} else if t.Index >= t.offset+t.Height {
+		t.offset = t.Index - t.Height + 1
+	}
+	if t.offset+t.Height > len(t.Content) {
+		t.offset = len(t.Content) - t.Height
+	}
+	return t.Content[t.offset : t.offset+t.Height]
+}
+
 // NextItem sets the index of the selected item to the next one in the content
 // list, wrapping around when it reaches the end.
 func (t *Table) NextItem() {
+	if len(t.Content) == 0 {
+		return
+	}
 	t.Index = (t.Index + 1) % len(t.Content)
 }
 
 // PreviousItem sets the index to the previous item in the content list wrapping
 // around to the end when it reaches the start.
 func (t *Table) PreviousItem() {
+	if len(t.Content) == 0 {
+		return
+	}
 	if t.Index-1 < 0 {
 		t.Index = len(t.Content) - 1
 	} else {
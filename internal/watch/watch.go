@@ -0,0 +1,144 @@
+// Package watch reports changes to the files below a directory. It polls the
+// directory tree, which works the same on every platform and file system, and
+// reports the changes in batches once the tree has stopped changing, so that a
+// git pull or a bulk edit is reported once rather than file by file.
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Watcher polls a directory tree for files that are added, changed or
+// removed.
+type Watcher struct {
+	// Changes receives the paths of the files that changed, sorted, once a
+	// poll finds no further changes. It is closed when the watcher is closed.
+	Changes <-chan []string
+
+	dir       string
+	interval  time.Duration
+	files     map[string]fileState
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watch starts watching the files below dir, looking for changes every
+// interval. Files and directories whose names start with a dot are ignored.
+func Watch(dir string, interval time.Duration) (*Watcher, error) {
+	files, err := scan(dir)
+	if err != nil {
+		return nil, err
+	}
+	changes := make(chan []string)
+	w := &Watcher{
+		Changes:  changes,
+		dir:      dir,
+		interval: interval,
+		files:    files,
+		stop:     make(chan struct{}),
+	}
+	go w.run(changes)
+	return w, nil
+}
+
+// Close stops the watcher. Changes that were not reported yet are dropped.
+func (w *Watcher) Close() {
+	w.closeOnce.Do(func() {
+		close(w.stop)
+	})
+}
+
+func (w *Watcher) run(changes chan<- []string) {
+	defer close(changes)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	pending := make(map[string]bool)
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+
+		files, err := scan(w.dir)
+		if err != nil {
+			// The directory may be replaced by a checkout, try again on
+			// the next tick.
+			continue
+		}
+		changed := diff(w.files, files)
+		w.files = files
+		for _, file := range changed {
+			pending[file] = true
+		}
+		// Wait for the tree to settle before reporting anything.
+		if len(changed) > 0 || len(pending) == 0 {
+			continue
+		}
+
+		batch := make([]string, 0, len(pending))
+		for file := range pending {
+			batch = append(batch, file)
+		}
+		sort.Strings(batch)
+		select {
+		case changes <- batch:
+			pending = make(map[string]bool)
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// scan records the modification time and size of every file below dir.
+func scan(dir string) (map[string]fileState, error) {
+	files := make(map[string]fileState)
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			// Files can disappear while the tree is walked.
+			if os.IsNotExist(err) && file != dir {
+				return nil
+			}
+			return err
+		}
+		if file != dir && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() {
+			files[file] = fileState{modTime: info.ModTime(), size: info.Size()}
+		}
+		return nil
+	})
+	return files, err
+}
+
+// diff returns the files that were added, changed or removed between two
+// scans.
+func diff(before, after map[string]fileState) []string {
+	var changed []string
+	for file, state := range after {
+		if old, ok := before[file]; !ok || !old.modTime.Equal(state.modTime) || old.size != state.size {
+			changed = append(changed, file)
+		}
+	}
+	for file := range before {
+		if _, ok := after[file]; !ok {
+			changed = append(changed, file)
+		}
+	}
+	return changed
+}
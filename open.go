@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/sudosays/hydra/pkg/data/hugo"
+)
+
+// postURL returns the address of a post: on the preview server if it is
+// running, otherwise on the published site.
+func postURL(blog hugo.Blog, post hugo.Post) (string, error) {
+	if previewRunning() {
+		return hugo.JoinURL(preview.URL(), blog.PostPath(post)), nil
+	}
+	if !strings.Contains(blog.Config.BaseURL, "://") {
+		return "", fmt.Errorf("the site has no baseURL, start the preview to open posts on it")
+	}
+	return blog.Permalink(post), nil
+}
+
+// openPost opens the address of a post in the browser and returns a status
+// message saying where it went.
+func openPost(blog hugo.Blog, post hugo.Post) (string, error) {
+	url, err := postURL(blog, post)
+	if err != nil {
+		return "", err
+	}
+	if err := openURL(url); err != nil {
+		return "", err
+	}
+	status := "Opened " + url
+	if post.Draft && !(previewRunning() && preview.Options.Drafts) {
+		status += ", but it is a draft and only `preview --drafts` serves drafts"
+	}
+	return status, nil
+}
+
+// openURL opens url with the browser set in the config, or the default
+// browser of the desktop. It does not wait for the browser to exit.
+func openURL(url string) error {
+	var cmd *exec.Cmd
+	switch {
+	case config.Browser != "":
+		args := strings.Fields(config.Browser)
+		cmd = exec.Command(args[0], append(args[1:], url)...)
+	case runtime.GOOS == "darwin":
+		cmd = exec.Command("open", url)
+	case runtime.GOOS == "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("opening the browser: %w", err)
+	}
+	go cmd.Wait()
+	return nil
+}
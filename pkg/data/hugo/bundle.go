@@ -0,0 +1,106 @@
+package hugo
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// readBundle marks the post as a leaf bundle if its file is the index of one,
+// and lists the resources of the bundle.
+func (post *Post) readBundle(sitePath string) error {
+	post.Bundle = isBundleIndex(path.Base(post.Path))
+	post.Resources = nil
+	if !post.Bundle {
+		return nil
+	}
+
+	dir := filepath.Join(sitePath, path.Dir(post.Path))
+	return filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if info.IsDir() || rel == path.Base(post.Path) {
+			return nil
+		}
+		post.Resources = append(post.Resources, rel)
+		return nil
+	})
+}
+
+// postRoot returns the path of what makes up a post on disk: the directory of
+// a leaf bundle, otherwise the content file itself.
+func postRoot(relPath string) string {
+	if isBundleIndex(path.Base(relPath)) {
+		return path.Dir(relPath)
+	}
+	return relPath
+}
+
+// MovePost moves a post to another section of the site, e.g. "notes" or
+// "blog/2021". A leaf bundle is moved together with its resources. It returns
+// the new path of the post.
+func (blog *Blog) MovePost(relPath, section string) (string, error) {
+	section = strings.Trim(path.Clean("/"+section), "/")
+	if section == "" {
+		return "", fmt.Errorf("a post needs a section to move to")
+	}
+	root := postRoot(relPath)
+	return blog.relocatePost(relPath, path.Join(blog.contentDir(), section, path.Base(root)))
+}
+
+// RenamePost changes the name of the file of a post, or of the directory of a
+// leaf bundle, keeping it in the same section. The extension of the file is
+// kept if name does not have one. It returns the new path of the post.
+func (blog *Blog) RenamePost(relPath, name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", fmt.Errorf("'%s' is not a valid name for a post", name)
+	}
+	root := postRoot(relPath)
+	if root == relPath && !isContentFile(name) {
+		name += path.Ext(relPath)
+	}
+	return blog.relocatePost(relPath, path.Join(path.Dir(root), name))
+}
+
+// relocatePost moves the file or bundle directory of the post at relPath to
+// target, refusing to overwrite anything.
+func (blog *Blog) relocatePost(relPath, target string) (string, error) {
+	root := postRoot(relPath)
+	source := filepath.Join(blog.Path, root)
+	if _, err := os.Stat(source); os.IsNotExist(err) {
+		return "", fmt.Errorf("%w: %s", ErrPostNotFound, relPath)
+	}
+	if target == root {
+		return relPath, nil
+	}
+	destination := filepath.Join(blog.Path, target)
+	if _, err := os.Stat(destination); err == nil {
+		return "", fmt.Errorf("cannot move the post to %s, it already exists", target)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(source, destination); err != nil {
+		return "", err
+	}
+	newPath := target
+	if root != relPath {
+		newPath = path.Join(target, path.Base(relPath))
+	}
+	if blog.useHugoList {
+		return newPath, blog.reload()
+	}
+	if err := blog.forgetPost(relPath); err != nil {
+		return "", err
+	}
+	return newPath, blog.refreshPost(newPath)
+}
@@ -0,0 +1,77 @@
+package hugo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// FilesChanged updates the posts after files in the content directory were
+// added, changed or removed outside hydra, for example by another editor or a
+// git pull. The files are given by their full path. Only the posts the files
+// belong to are read again: a changed resource of a page bundle reloads the
+// bundle. If a post fails to load the first error is returned, once all the
+// other posts are updated.
+func (blog *Blog) FilesChanged(files []string) error {
+	if blog.useHugoList {
+		return blog.reload()
+	}
+
+	// The posts to read again. Bundles are read even if their index file did
+	// not change, since their list of resources did.
+	refresh := make(map[string]bool)
+	for _, file := range files {
+		rel, err := filepath.Rel(blog.Path, file)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		inContent := strings.TrimPrefix(rel, blog.contentDir()+"/")
+		if inContent == rel {
+			continue
+		}
+		if index := blog.bundleIndex(rel); index != "" {
+			refresh[index] = true
+			continue
+		}
+		name := path.Base(rel)
+		if isContentFile(name) && !strings.HasPrefix(name, "_index.") && !strings.Contains("/"+inContent, "/.") {
+			if _, ok := refresh[rel]; !ok {
+				refresh[rel] = false
+			}
+		}
+	}
+
+	var firstErr error
+	for relPath, bundle := range refresh {
+		if bundle {
+			delete(blog.modTimes, relPath)
+		}
+		if err := blog.Refresh(relPath); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("reloading %s failed: %w", relPath, err)
+			}
+		}
+	}
+	return firstErr
+}
+
+// bundleIndex returns the index file of the leaf bundle a file is a resource
+// of, or an empty string if it is not in a bundle.
+func (blog Blog) bundleIndex(relPath string) string {
+	for dir := path.Dir(relPath); strings.HasPrefix(dir, blog.contentDir()+"/"); dir = path.Dir(dir) {
+		entries, err := ioutil.ReadDir(filepath.Join(blog.Path, dir))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			index := path.Join(dir, entry.Name())
+			if !entry.IsDir() && isBundleIndex(entry.Name()) && index != relPath {
+				return index
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,285 @@
+package hugo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// siteConfigFiles are the names Hugo looks for when reading the site
+// configuration, in order of preference.
+var siteConfigFiles = []string{
+	"hugo.toml", "hugo.yaml", "hugo.yml", "hugo.json",
+	"config.toml", "config.yaml", "config.yml", "config.json",
+}
+
+// siteConfigDir is the configuration directory Hugo reads for every
+// environment. Its files are merged with the configuration file in the root
+// of the site.
+const siteConfigDir = "config/_default"
+
+// Hugo's defaults for the settings of a site.
+const (
+	defaultContentDir      = "content"
+	defaultPublishDir      = "public"
+	defaultContentLanguage = "en"
+)
+
+// defaultTaxonomies are used by Hugo when the site does not configure any.
+var defaultTaxonomies = map[string]string{
+	"tag":      "tags",
+	"category": "categories",
+}
+
+// A SiteConfig holds the settings of a Hugo site that hydra uses. Settings the
+// site does not set have Hugo's default values.
+type SiteConfig struct {
+	Title   string
+	BaseURL string
+	// ContentDir and PublishDir are the directories, relative to the site,
+	// that hold the content and that the site is built into.
+	ContentDir string
+	PublishDir string
+	// DefaultLanguage is the code of the main language of the site, and
+	// Languages lists the languages the site is translated into by weight. It
+	// is empty if the site does not configure any languages.
+	DefaultLanguage string
+	Languages       []Language
+	// Taxonomies maps the singular name of each taxonomy of the site to the
+	// plural name used in front matter, e.g. "tag" to "tags".
+	Taxonomies map[string]string
+	// Permalinks maps sections to the URL pattern of their pages, e.g.
+	// "/:year/:month/:slug/".
+	Permalinks         map[string]string
+	UglyURLs           bool
+	DisablePathToLower bool
+	// Files lists the configuration files the settings were read from,
+	// relative to the site. It is empty for a site that has none.
+	Files []string
+}
+
+// A Language is one of the languages a site is translated into.
+type Language struct {
+	Code, Name, Title string
+	Weight            int
+}
+
+// readSiteConfig reads the configuration of the site in sitePath, from the
+// configuration file in its root and the files in config/_default. A setting
+// in the root file wins over the same setting in config/_default. A site
+// without any configuration gets Hugo's defaults, but a configuration file
+// that cannot be read is an error: with the wrong content directory the site
+// would look empty.
+func readSiteConfig(sitePath string) (SiteConfig, error) {
+	values, files, err := readSiteConfigValues(sitePath)
+	if err != nil {
+		return SiteConfig{}, err
+	}
+
+	config := SiteConfig{
+		Title:              toString(values["title"]),
+		BaseURL:            toString(values["baseurl"]),
+		ContentDir:         toString(values["contentdir"]),
+		PublishDir:         toString(values["publishdir"]),
+		DefaultLanguage:    toString(values["defaultcontentlanguage"]),
+		Languages:          siteLanguages(values),
+		Taxonomies:         siteTaxonomies(values),
+		Permalinks:         sitePermalinks(values),
+		UglyURLs:           toBool(values["uglyurls"]),
+		DisablePathToLower: toBool(values["disablepathtolower"]),
+		Files:              files,
+	}
+	if config.ContentDir == "" {
+		config.ContentDir = defaultContentDir
+	}
+	if config.PublishDir == "" {
+		config.PublishDir = defaultPublishDir
+	}
+	if config.DefaultLanguage == "" {
+		config.DefaultLanguage = defaultContentLanguage
+	}
+	return config, nil
+}
+
+// readSiteConfigValues decodes and merges the configuration files of a site,
+// and returns the names of the files it read. All keys are lowercased, as Hugo
+// treats them case-insensitively. A site without any configuration gives an
+// empty map.
+func readSiteConfigValues(sitePath string) (map[string]interface{}, []string, error) {
+	values := make(map[string]interface{})
+	var files []string
+	for _, name := range siteConfigFiles {
+		file := filepath.Join(sitePath, name)
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			continue
+		}
+		root, err := decodeConfigFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		values = root
+		files = append(files, name)
+		break
+	}
+
+	// Files in the config directory are named after the key they set, e.g.
+	// `params.toml` or `languages.yaml`, except for hugo.* and config.* which
+	// hold settings of any key. Files for a single language, such as
+	// `menus.en.toml`, are skipped.
+	entries, err := ioutil.ReadDir(filepath.Join(sitePath, siteConfigDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		key := strings.ToLower(strings.TrimSuffix(entry.Name(), ext))
+		if entry.IsDir() || !isConfigExt(ext) || strings.Contains(key, ".") {
+			continue
+		}
+		fileValues, err := decodeConfigFile(filepath.Join(sitePath, siteConfigDir, entry.Name()))
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, path.Join(siteConfigDir, entry.Name()))
+		if key != "hugo" && key != "config" {
+			fileValues = map[string]interface{}{key: fileValues}
+		}
+		mergeMissing(values, fileValues)
+	}
+	return values, files, nil
+}
+
+func isConfigExt(ext string) bool {
+	switch ext {
+	case ".toml", ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// decodeConfigFile decodes a TOML, YAML or JSON configuration file, going by
+// its extension.
+func decodeConfigFile(file string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]interface{}
+	switch filepath.Ext(file) {
+	case ".toml":
+		values, err = parseTOML(data)
+	case ".json":
+		err = json.Unmarshal(data, &values)
+	default:
+		values, err = parseYAML(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading the site config %s: %w", filepath.Base(file), err)
+	}
+	return lowerKeysDeep(values), nil
+}
+
+// mergeMissing adds the settings of src that dst does not have to dst,
+// merging tables that are in both.
+func mergeMissing(dst, src map[string]interface{}) {
+	for key, value := range src {
+		existing, ok := dst[key]
+		if !ok {
+			dst[key] = value
+			continue
+		}
+		existingTable, ok1 := existing.(map[string]interface{})
+		table, ok2 := value.(map[string]interface{})
+		if ok1 && ok2 {
+			mergeMissing(existingTable, table)
+		}
+	}
+}
+
+func lowerKeys(values map[string]interface{}) map[string]interface{} {
+	lowered := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		lowered[strings.ToLower(key)] = value
+	}
+	return lowered
+}
+
+// lowerKeysDeep lowercases the keys of values and of all the tables in it.
+func lowerKeysDeep(values map[string]interface{}) map[string]interface{} {
+	lowered := lowerKeys(values)
+	for key, value := range lowered {
+		if table, ok := value.(map[string]interface{}); ok {
+			lowered[key] = lowerKeysDeep(table)
+		}
+	}
+	return lowered
+}
+
+// siteTaxonomies returns the taxonomies of a site, mapping the singular name
+// to the plural name used in front matter.
+func siteTaxonomies(config map[string]interface{}) map[string]string {
+	configured, ok := config["taxonomies"].(map[string]interface{})
+	if !ok {
+		taxonomies := make(map[string]string, len(defaultTaxonomies))
+		for singular, plural := range defaultTaxonomies {
+			taxonomies[singular] = plural
+		}
+		return taxonomies
+	}
+	taxonomies := make(map[string]string, len(configured))
+	for singular, plural := range configured {
+		taxonomies[strings.ToLower(singular)] = strings.ToLower(toString(plural))
+	}
+	return taxonomies
+}
+
+// sitePermalinks reads the `permalinks` setting of a site, which maps sections
+// to URL patterns, e.g. `posts = "/:year/:month/:slug/"`. Newer versions of
+// Hugo nest the patterns of pages under `page`, which is preferred.
+func sitePermalinks(config map[string]interface{}) map[string]string {
+	configured, ok := config["permalinks"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if pages, ok := configured["page"].(map[string]interface{}); ok {
+		configured = pages
+	}
+	permalinks := make(map[string]string, len(configured))
+	for section, pattern := range configured {
+		if pattern, ok := pattern.(string); ok {
+			permalinks[section] = pattern
+		}
+	}
+	return permalinks
+}
+
+// siteLanguages returns the languages of a site, sorted by weight and then by
+// code like Hugo does.
+func siteLanguages(config map[string]interface{}) []Language {
+	configured, ok := config["languages"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	var languages []Language
+	for code, settings := range configured {
+		values, _ := settings.(map[string]interface{})
+		languages = append(languages, Language{
+			Code:   code,
+			Name:   toString(values["languagename"]),
+			Title:  toString(values["title"]),
+			Weight: toInt(values["weight"]),
+		})
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		if languages[i].Weight != languages[j].Weight {
+			return languages[i].Weight < languages[j].Weight
+		}
+		return languages[i].Code < languages[j].Code
+	})
+	return languages
+}
@@ -0,0 +1,217 @@
+package hugo
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadSiteConfigDefaults(t *testing.T) {
+	config, err := readSiteConfig(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := SiteConfig{
+		ContentDir:      "content",
+		PublishDir:      "public",
+		DefaultLanguage: "en",
+		Taxonomies:      map[string]string{"tag": "tags", "category": "categories"},
+	}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("got %+v, want %+v", config, want)
+	}
+}
+
+func TestReadSiteConfig(t *testing.T) {
+	want := SiteConfig{
+		Title:           "My Site",
+		BaseURL:         "https://example.org/",
+		ContentDir:      "src",
+		PublishDir:      "docs",
+		DefaultLanguage: "nl",
+		Languages: []Language{
+			{Code: "nl", Name: "Nederlands", Weight: 1},
+			{Code: "en", Name: "English", Title: "My Site in English", Weight: 2},
+		},
+		Taxonomies: map[string]string{"tag": "tags", "series": "series"},
+		Permalinks: map[string]string{"blog": "/:year/:slug/"},
+		UglyURLs:   true,
+	}
+	for name, content := range map[string]string{
+		"hugo.toml": `title = "My Site"
+baseURL = "https://example.org/"
+contentDir = "src"
+publishDir = "docs"
+defaultContentLanguage = "nl"
+uglyURLs = true
+
+[languages.en]
+languageName = "English"
+title = "My Site in English"
+weight = 2
+[languages.nl]
+languageName = "Nederlands"
+weight = 1
+
+[taxonomies]
+tag = "tags"
+series = "series"
+
+[permalinks.page]
+blog = "/:year/:slug/"
+`,
+		"config.yaml": `title: My Site
+baseURL: https://example.org/
+contentDir: src
+publishDir: docs
+defaultContentLanguage: nl
+uglyURLs: true
+languages:
+  en:
+    languageName: English
+    title: My Site in English
+    weight: 2
+  nl:
+    languageName: Nederlands
+    weight: 1
+taxonomies:
+  tag: tags
+  series: series
+permalinks:
+  blog: /:year/:slug/
+`,
+		"config.json": `{
+  "title": "My Site", "baseURL": "https://example.org/",
+  "contentDir": "src", "publishDir": "docs",
+  "defaultContentLanguage": "nl", "uglyURLs": true,
+  "languages": {
+    "en": {"languageName": "English", "title": "My Site in English", "weight": 2},
+    "nl": {"languageName": "Nederlands", "weight": 1}
+  },
+  "taxonomies": {"tag": "tags", "series": "series"},
+  "permalinks": {"blog": "/:year/:slug/"}
+}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, dir, name, content)
+			config, err := readSiteConfig(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := want
+			want.Files = []string{name}
+			if !reflect.DeepEqual(config, want) {
+				t.Errorf("got\n%+v\nwant\n%+v", config, want)
+			}
+		})
+	}
+}
+
+func TestReadSiteConfigMergeOrder(t *testing.T) {
+	dir := t.TempDir()
+	// hugo.toml is read rather than config.toml.
+	writeFile(t, dir, "hugo.toml", "title = \"Root\"\n[permalinks]\nblog = \"/root/:slug/\"\n")
+	writeFile(t, dir, "config.toml", "title = \"Ignored\"\nbaseURL = \"https://ignored.org/\"\n")
+	// The root file wins, and config/_default fills in the rest, with tables
+	// merged key by key.
+	writeFile(t, dir, "config/_default/hugo.toml", "title = \"Default\"\nbaseURL = \"https://example.org/\"\n")
+	writeFile(t, dir, "config/_default/permalinks.toml", "blog = \"/default/:slug/\"\nnotes = \"/notes/:slug/\"\n")
+	writeFile(t, dir, "config/_default/taxonomies.yaml", "tag: tags\n")
+	writeFile(t, dir, "config/_default/languages.json", `{"en": {"weight": 1}}`)
+	// Files for a single language and other files are skipped.
+	writeFile(t, dir, "config/_default/menus.en.toml", "not toml at all")
+	writeFile(t, dir, "config/_default/README.md", "not a config file")
+
+	config, err := readSiteConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Title != "Root" || config.BaseURL != "https://example.org/" {
+		t.Errorf("the title is %q and baseURL %q", config.Title, config.BaseURL)
+	}
+	wantPermalinks := map[string]string{"blog": "/root/:slug/", "notes": "/notes/:slug/"}
+	if !reflect.DeepEqual(config.Permalinks, wantPermalinks) {
+		t.Errorf("the permalinks are %v, want %v", config.Permalinks, wantPermalinks)
+	}
+	if !reflect.DeepEqual(config.Taxonomies, map[string]string{"tag": "tags"}) {
+		t.Errorf("the taxonomies are %v", config.Taxonomies)
+	}
+	if len(config.Languages) != 1 || config.Languages[0].Code != "en" {
+		t.Errorf("the languages are %+v", config.Languages)
+	}
+	wantFiles := []string{"hugo.toml", "config/_default/hugo.toml", "config/_default/languages.json",
+		"config/_default/permalinks.toml", "config/_default/taxonomies.yaml"}
+	if !reflect.DeepEqual(config.Files, wantFiles) {
+		t.Errorf("the files read are %q, want %q", config.Files, wantFiles)
+	}
+}
+
+func TestReadSiteConfigYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"document markers", "---\ntitle: My Site\nbaseURL: https://example.org/\n...\nignored: true\n"},
+		{"document start with comment", "--- # the site\ntitle: My Site\nbaseURL: https://example.org/\n---\nsecond: document\n"},
+		{"anchors and aliases", `defaults: &defaults
+  title: Not used
+  baseURL: https://example.org/
+site:
+  <<: *defaults
+  title: My Site
+title: &title My Site
+baseURL: *title
+`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, err := parseYAML([]byte(test.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if values["title"] != "My Site" {
+				t.Errorf("the title is %#v in %v", values["title"], values)
+			}
+			for key := range values {
+				if key == "ignored" || key == "second" {
+					t.Errorf("%s was read from past the end of the document", key)
+				}
+			}
+		})
+	}
+
+	values, err := parseYAML([]byte(tests[2].content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"title": "My Site", "baseURL": "https://example.org/"}
+	if !reflect.DeepEqual(values["site"], want) {
+		t.Errorf("the merged map is %v, want %v", values["site"], want)
+	}
+	if values["baseURL"] != "My Site" {
+		t.Errorf("the alias is %#v", values["baseURL"])
+	}
+	if _, err := parseYAML([]byte("title: *missing\n")); err == nil {
+		t.Error("an unknown alias was accepted")
+	}
+}
+
+func TestUnreadableSiteConfig(t *testing.T) {
+	for name, content := range map[string]string{
+		"config.yaml":                 "title: [not closed\n",
+		"hugo.toml":                   "contentDir = \n",
+		"config/_default/params.json": "{",
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, dir, name, content)
+			writeFile(t, dir, "content/blog/post.md", "---\ntitle: Post\n---\n")
+			_, err := Load(dir)
+			if err == nil || !strings.Contains(err.Error(), filepath.Base(name)) {
+				t.Errorf("loading the site gave %v, want an error naming %s", err, name)
+			}
+		})
+	}
+}
@@ -0,0 +1,380 @@
+package hugo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// contentExtensions are the file extensions Hugo renders as pages.
+var contentExtensions = map[string]bool{
+	"md": true, "markdown": true, "mdown": true, "mkd": true, "mkdn": true,
+	"org": true, "html": true, "htm": true, "ad": true, "adoc": true,
+	"asciidoc": true, "pdc": true, "pandoc": true, "rst": true,
+}
+
+// dateLayouts are the date formats accepted in front matter, tried in order.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02 Mon 15:04",
+	"2006-01-02 Mon",
+	"2006-01-02",
+}
+
+func isContentFile(name string) bool {
+	ext := strings.TrimPrefix(path.Ext(name), ".")
+	return contentExtensions[strings.ToLower(ext)]
+}
+
+// isBundleIndex reports whether the file is the index of a leaf bundle, e.g.
+// `my-post/index.md`.
+func isBundleIndex(name string) bool {
+	return isContentFile(name) && strings.TrimSuffix(name, path.Ext(name)) == "index"
+}
+
+// loadPosts walks the content directory of the site and parses the front
+// matter of every page it finds. Posts are sorted newest first. Files with
+// invalid front matter are skipped with a warning to Logger.
+func (blog Blog) loadPosts() ([]Post, error) {
+	var posts []Post
+	err := walkContent(blog.Path, blog.contentDir(), func(relPath string) {
+		post, err := blog.readPost(relPath)
+		if err != nil {
+			Logger.Printf("Skipping %s: %s\n", relPath, err)
+			return
+		}
+		posts = append(posts, post)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortPosts(posts)
+	return posts, nil
+}
+
+// walkContent calls fn with the site relative path of every page below dir.
+// Section pages (`_index.*`) are skipped, and only the index of a leaf bundle
+// is visited since the other files in a bundle are resources.
+func walkContent(sitePath, dir string, fn func(relPath string)) error {
+	entries, err := ioutil.ReadDir(filepath.Join(sitePath, dir))
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() && isBundleIndex(entry.Name()) {
+			fn(path.Join(dir, entry.Name()))
+			return nil
+		}
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case strings.HasPrefix(name, "."):
+			continue
+		case entry.IsDir():
+			if err := walkContent(sitePath, path.Join(dir, name), fn); err != nil {
+				return err
+			}
+		case isContentFile(name) && !strings.HasPrefix(name, "_index."):
+			fn(path.Join(dir, name))
+		}
+	}
+	return nil
+}
+
+// sectionOf returns the top level section of a content file, which is the
+// first directory below the content directory.
+func (blog Blog) sectionOf(relPath string) string {
+	rel := strings.TrimPrefix(relPath, blog.contentDir()+"/")
+	if i := strings.Index(rel, "/"); i >= 0 {
+		return rel[:i]
+	}
+	return ""
+}
+
+// readPost parses the front matter of the content file at relPath.
+func (blog Blog) readPost(relPath string) (Post, error) {
+	content, err := ioutil.ReadFile(filepath.Join(blog.Path, relPath))
+	if err != nil {
+		return Post{}, err
+	}
+	format, fm, _, err := splitFrontMatter(content)
+	if err != nil {
+		return Post{}, err
+	}
+	values, err := parseFrontMatter(format, fm)
+	if err != nil {
+		return Post{}, err
+	}
+	post := newPost(relPath, values)
+	post.Section = blog.sectionOf(relPath)
+	post.Format = format
+	return post, post.readBundle(blog.Path)
+}
+
+// postKeys are the (lowercased) front matter keys that have a field on Post.
+var postKeys = map[string]bool{
+	"title": true, "date": true, "draft": true, "tags": true,
+	"categories": true, "series": true, "description": true, "summary": true,
+	"slug": true, "weight": true, "aliases": true, "publishdate": true,
+	"expirydate": true, "lastmod": true,
+}
+
+// newPost fills in a Post from decoded front matter values. The section of the
+// post is left for the caller to fill in.
+func newPost(relPath string, values map[string]interface{}) Post {
+	post := Post{
+		Path:        relPath,
+		Title:       toString(values["title"]),
+		Date:        toDate(values["date"]),
+		Draft:       toBool(values["draft"]),
+		Tags:        toStrings(values["tags"]),
+		Categories:  toStrings(values["categories"]),
+		Series:      toStrings(values["series"]),
+		Description: toString(values["description"]),
+		Summary:     toString(values["summary"]),
+		Slug:        toString(values["slug"]),
+		Weight:      toInt(values["weight"]),
+		Aliases:     toStrings(values["aliases"]),
+		PublishDate: toDate(values["publishdate"]),
+		ExpiryDate:  toDate(values["expirydate"]),
+		Lastmod:     toDate(values["lastmod"]),
+		Params:      make(map[string]interface{}),
+	}
+	for key, value := range values {
+		if !postKeys[key] {
+			post.Params[key] = value
+		}
+	}
+	return post
+}
+
+// Terms returns the terms a post uses for a taxonomy, given by its plural
+// name as used in front matter.
+func (post Post) Terms(taxonomy string) []string {
+	switch strings.ToLower(taxonomy) {
+	case "tags":
+		return post.Tags
+	case "categories":
+		return post.Categories
+	case "series":
+		return post.Series
+	}
+	return toStrings(post.Params[strings.ToLower(taxonomy)])
+}
+
+// SetTerms replaces the terms a post uses for a taxonomy. Params is copied
+// before it is changed, since it may be shared with Blog.Posts.
+func (post *Post) SetTerms(taxonomy string, terms []string) {
+	switch strings.ToLower(taxonomy) {
+	case "tags":
+		post.Tags = terms
+	case "categories":
+		post.Categories = terms
+	case "series":
+		post.Series = terms
+	default:
+		params := make(map[string]interface{}, len(post.Params)+1)
+		for key, value := range post.Params {
+			params[key] = value
+		}
+		params[strings.ToLower(taxonomy)] = terms
+		post.Params = params
+	}
+}
+
+// postFields are the front matter keys written for the fields of a Post, in
+// the spelling used by the Hugo documentation.
+var postFields = []string{
+	"title", "date", "draft", "tags", "categories", "series", "description",
+	"summary", "slug", "weight", "aliases", "publishDate", "expiryDate",
+	"lastmod",
+}
+
+// fields returns the front matter values of a post keyed by postFields.
+func (post Post) fields() map[string]interface{} {
+	return map[string]interface{}{
+		"title":       post.Title,
+		"date":        post.Date,
+		"draft":       post.Draft,
+		"tags":        post.Tags,
+		"categories":  post.Categories,
+		"series":      post.Series,
+		"description": post.Description,
+		"summary":     post.Summary,
+		"slug":        post.Slug,
+		"weight":      post.Weight,
+		"aliases":     post.Aliases,
+		"publishDate": post.PublishDate,
+		"expiryDate":  post.ExpiryDate,
+		"lastmod":     post.Lastmod,
+	}
+}
+
+// A change is a front matter key that needs to be written. A nil value means
+// the key should be removed.
+type change struct {
+	key   string
+	value interface{}
+}
+
+// postChanges lists the front matter keys that differ between two versions
+// of a post.
+func postChanges(old, updated Post) []change {
+	var changes []change
+	oldFields, newFields := old.fields(), updated.fields()
+	for _, key := range postFields {
+		if !sameValue(oldFields[key], newFields[key]) {
+			changes = append(changes, change{key, emptyToNil(newFields[key])})
+		}
+	}
+
+	keys := make(map[string]interface{})
+	for key := range old.Params {
+		keys[key] = nil
+	}
+	for key := range updated.Params {
+		keys[key] = nil
+	}
+	for _, key := range sortedKeys(keys) {
+		if !sameValue(old.Params[key], updated.Params[key]) {
+			changes = append(changes, change{key, emptyToNil(updated.Params[key])})
+		}
+	}
+	return changes
+}
+
+// onlyKeys returns the changes whose key is also changed in wanted.
+func onlyKeys(changes, wanted []change) []change {
+	keys := make(map[string]bool, len(wanted))
+	for _, c := range wanted {
+		keys[c.key] = true
+	}
+	var kept []change
+	for _, c := range changes {
+		if keys[c.key] {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+func sameValue(a, b interface{}) bool {
+	return reflect.DeepEqual(emptyToNil(a), emptyToNil(b))
+}
+
+// emptyToNil normalises a value and maps empty values to nil, so that clearing
+// a field removes the key rather than writing an empty value. Booleans are
+// always kept.
+func emptyToNil(value interface{}) interface{} {
+	value = normaliseValue(value)
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+	case int:
+		if v == 0 {
+			return nil
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+	}
+	return value
+}
+
+func sortPosts(posts []Post) {
+	sort.SliceStable(posts, func(i, j int) bool {
+		if posts[i].Date != posts[j].Date {
+			return parseDate(posts[i].Date).After(parseDate(posts[j].Date))
+		}
+		return posts[i].Title < posts[j].Title
+	})
+}
+
+func parseDate(s string) time.Time {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%g", v)
+	}
+	return fmt.Sprint(value)
+}
+
+// toDate normalises a front matter date to RFC3339, which is what `hugo list`
+// reports. Dates that cannot be parsed are returned as they are.
+func toDate(value interface{}) string {
+	s := strings.Trim(toString(value), "<>[] ")
+	if t := parseDate(s); !t.IsZero() {
+		return t.Format(time.RFC3339)
+	}
+	return s
+}
+
+func toStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			list = append(list, toString(item))
+		}
+		return list
+	case []string:
+		return v
+	}
+	return []string{toString(value)}
+}
+
+func toInt(value interface{}) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	case string:
+		i, _ := strconv.Atoi(strings.TrimSpace(v))
+		return i
+	}
+	return 0
+}
+
+func toBool(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(strings.TrimSpace(v), "true")
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package hugo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Binary is the hugo executable that is run. It can be changed to use a
+// specific version of Hugo, or a stand-in for it.
+var Binary = "hugo"
+
+// Logger receives the warnings that do not stop an operation, such as posts
+// that are skipped or a search index that cannot be saved. It writes to stderr
+// unless a program that owns the terminal sends it elsewhere.
+var Logger = log.New(os.Stderr, "", log.LstdFlags)
+
+// ErrHugoNotFound is returned when a command needs the hugo executable and it
+// is not installed or not in $PATH.
+var ErrHugoNotFound = errors.New("hugo was not found, is it installed and in your $PATH?")
+
+// ErrPostNotFound is returned when a post does not exist in the site.
+var ErrPostNotFound = errors.New("no such post")
+
+// ErrPostExists is returned when creating a post would overwrite another one.
+var ErrPostExists = errors.New("a post with that name already exists")
+
+// A HugoError is returned when the hugo executable fails. Output holds whatever
+// hugo printed to stderr, which usually explains what went wrong.
+type HugoError struct {
+	Args   []string
+	Output string
+	Err    error
+}
+
+func (e *HugoError) Error() string {
+	msg := fmt.Sprintf("hugo %s: %s", strings.Join(e.Args, " "), e.Err)
+	if e.Output != "" {
+		msg += "\n" + e.Output
+	}
+	return msg
+}
+
+func (e *HugoError) Unwrap() error {
+	return e.Err
+}
+
+// runHugo runs hugo in dir and returns what it printed to stdout.
+func runHugo(dir string, args ...string) (string, error) {
+	cmd := exec.Command(Binary, args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if errors.Is(err, exec.ErrNotFound) {
+		return "", ErrHugoNotFound
+	}
+	if err != nil {
+		return string(out), &HugoError{Args: args, Output: strings.TrimSpace(stderr.String()), Err: err}
+	}
+	return string(out), nil
+}
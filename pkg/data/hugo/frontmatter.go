@@ -0,0 +1,451 @@
+package hugo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Format identifies the front matter syntax used by a content file.
+type Format int
+
+// The front matter formats understood by Hugo. NoFrontMatter is used for
+// content files that do not start with a front matter block.
+const (
+	NoFrontMatter Format = iota
+	YAML
+	TOML
+	JSON
+	Org
+)
+
+func (f Format) String() string {
+	switch f {
+	case YAML:
+		return "yaml"
+	case TOML:
+		return "toml"
+	case JSON:
+		return "json"
+	case Org:
+		return "org"
+	}
+	return "none"
+}
+
+var errUnterminatedFrontMatter = errors.New("front matter is not terminated")
+
+// splitFrontMatter separates the front matter block at the start of a content
+// file from the body of the file. The returned front matter still includes its
+// delimiters.
+func splitFrontMatter(content []byte) (Format, []byte, []byte, error) {
+	content = bytes.TrimPrefix(content, []byte("\ufeff"))
+
+	switch {
+	case hasDelimiter(content, "---"):
+		return splitDelimited(YAML, content, "---")
+	case hasDelimiter(content, "+++"):
+		return splitDelimited(TOML, content, "+++")
+	case bytes.HasPrefix(content, []byte("{")):
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return JSON, nil, nil, err
+		}
+		end := int(decoder.InputOffset())
+		return JSON, content[:end], content[end:], nil
+	case bytes.HasPrefix(content, []byte("#+")):
+		end := 0
+		for end < len(content) && bytes.HasPrefix(content[end:], []byte("#+")) {
+			next := bytes.IndexByte(content[end:], '\n')
+			if next < 0 {
+				end = len(content)
+				break
+			}
+			end += next + 1
+		}
+		return Org, content[:end], content[end:], nil
+	}
+
+	return NoFrontMatter, nil, content, nil
+}
+
+func hasDelimiter(content []byte, delim string) bool {
+	line := firstLine(content)
+	return strings.TrimRight(string(line), " \t\r") == delim
+}
+
+func firstLine(content []byte) []byte {
+	if i := bytes.IndexByte(content, '\n'); i >= 0 {
+		return content[:i]
+	}
+	return content
+}
+
+func splitDelimited(format Format, content []byte, delim string) (Format, []byte, []byte, error) {
+	start := len(firstLine(content)) + 1
+	offset := start
+	for offset <= len(content) {
+		line := firstLine(content[offset:])
+		if strings.TrimRight(string(line), " \t\r") == delim {
+			end := offset + len(line)
+			if end < len(content) {
+				end++
+			}
+			return format, content[:end], content[end:], nil
+		}
+		offset += len(line) + 1
+	}
+	return format, nil, nil, errUnterminatedFrontMatter
+}
+
+// frontMatterBody strips the delimiters from a front matter block so that only
+// the data remains.
+func frontMatterBody(format Format, fm []byte) []byte {
+	switch format {
+	case YAML, TOML:
+		lines := bytes.SplitAfter(bytes.TrimRight(fm, "\r\n"), []byte("\n"))
+		if len(lines) < 2 {
+			return nil
+		}
+		return bytes.Join(lines[1:len(lines)-1], nil)
+	}
+	return fm
+}
+
+// parseFrontMatter decodes a front matter block (including delimiters) into a
+// map. Top level keys are lowercased, as Hugo treats them case-insensitively.
+func parseFrontMatter(format Format, fm []byte) (map[string]interface{}, error) {
+	var values map[string]interface{}
+	var err error
+
+	data := frontMatterBody(format, fm)
+	switch format {
+	case YAML:
+		values, err = parseYAML(data)
+	case TOML:
+		values, err = parseTOML(data)
+	case JSON:
+		err = json.Unmarshal(data, &values)
+	case Org:
+		values = parseOrg(data)
+	default:
+		values = make(map[string]interface{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s front matter: %w", format, err)
+	}
+
+	return lowerKeys(values), nil
+}
+
+// Org mode
+
+func parseOrg(data []byte) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "#+") {
+			continue
+		}
+		colon := strings.Index(line, ":")
+		if colon < 0 {
+			continue
+		}
+		key := strings.ToLower(line[2:colon])
+		value := strings.TrimSpace(line[colon+1:])
+		switch {
+		case strings.HasSuffix(key, "[]"):
+			values[key[:len(key)-2]] = toInterfaces(strings.Fields(value))
+		case key == "tags" || key == "categories" || key == "aliases":
+			values[key] = toInterfaces(strings.Fields(value))
+		default:
+			values[key] = value
+		}
+	}
+	return values
+}
+
+func toInterfaces(items []string) []interface{} {
+	list := make([]interface{}, len(items))
+	for i, item := range items {
+		list[i] = item
+	}
+	return list
+}
+
+// YAML
+
+// yamlTextKeys are the top level keys whose values are always read as text, so
+// that a title such as `1e3` or `2021` is kept as it was written rather than
+// turned into a number.
+var yamlTextKeys = map[string]bool{
+	"title": true, "linktitle": true, "description": true, "summary": true, "slug": true,
+}
+
+// parseYAML decodes the first document of a YAML file into a map.
+func parseYAML(data []byte) (map[string]interface{}, error) {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(&doc); err != nil {
+		if err == io.EOF {
+			return make(map[string]interface{}), nil
+		}
+		return nil, err
+	}
+	root := &doc
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+	if resolveYAMLAlias(root).Kind != yaml.MappingNode {
+		if root.ShortTag() == "!!null" {
+			return make(map[string]interface{}), nil
+		}
+		return nil, fmt.Errorf("expected keys and values, not %q", root.Value)
+	}
+	value, err := yamlValue(root, yamlTextKeys)
+	if err != nil {
+		return nil, err
+	}
+	return value.(map[string]interface{}), nil
+}
+
+// yamlValue converts a YAML node into the values the rest of the package uses:
+// strings, bools, ints, float64s, []interface{} and map[string]interface{}.
+// Dates are kept as the text they were written as. The values of textKeys in
+// a map are kept as text as well.
+func yamlValue(node *yaml.Node, textKeys map[string]bool) (interface{}, error) {
+	node = resolveYAMLAlias(node)
+	switch node.Kind {
+	case yaml.MappingNode:
+		return yamlMap(node, textKeys)
+	case yaml.SequenceNode:
+		list := make([]interface{}, 0, len(node.Content))
+		for _, item := range node.Content {
+			value, err := yamlValue(item, nil)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	case yaml.ScalarNode:
+		switch node.ShortTag() {
+		case "!!null":
+			return nil, nil
+		case "!!bool", "!!int", "!!float":
+			var value interface{}
+			if err := node.Decode(&value); err != nil {
+				return nil, err
+			}
+			return normaliseValue(value), nil
+		}
+		return node.Value, nil
+	}
+	return nil, fmt.Errorf("line %d: unexpected yaml node", node.Line)
+}
+
+// yamlMap converts a mapping node. The maps given to the merge key `<<` add
+// the keys the mapping does not set itself, the first map winning.
+func yamlMap(node *yaml.Node, textKeys map[string]bool) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(node.Content)/2)
+	var merges []*yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, valueNode := resolveYAMLAlias(node.Content[i]), node.Content[i+1]
+		if key.ShortTag() == "!!merge" {
+			merged := resolveYAMLAlias(valueNode)
+			if merged.Kind == yaml.SequenceNode {
+				merges = append(merges, merged.Content...)
+			} else {
+				merges = append(merges, merged)
+			}
+			continue
+		}
+		text := resolveYAMLAlias(valueNode)
+		if textKeys[strings.ToLower(key.Value)] && text.Kind == yaml.ScalarNode && text.ShortTag() != "!!null" {
+			values[key.Value] = text.Value
+			continue
+		}
+		value, err := yamlValue(valueNode, nil)
+		if err != nil {
+			return nil, err
+		}
+		values[key.Value] = value
+	}
+	for _, merge := range merges {
+		if resolveYAMLAlias(merge).Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("line %d: << needs a map", merge.Line)
+		}
+		merged, err := yamlMap(resolveYAMLAlias(merge), nil)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range merged {
+			if _, ok := values[key]; !ok {
+				values[key] = value
+			}
+		}
+	}
+	return values, nil
+}
+
+func resolveYAMLAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+func isYAMLListItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitYAMLKey splits a `key: value` line. The value is returned with any
+// trailing comment removed.
+func splitYAMLKey(text string) (string, string, bool) {
+	var key string
+	rest := text
+	if strings.HasPrefix(text, "\"") || strings.HasPrefix(text, "'") {
+		end := strings.Index(text[1:], text[:1])
+		if end < 0 {
+			return "", "", false
+		}
+		key = text[1 : end+1]
+		rest = text[end+2:]
+		if !strings.HasPrefix(rest, ":") {
+			return "", "", false
+		}
+		rest = rest[1:]
+	} else {
+		colon := strings.Index(text, ": ")
+		if colon < 0 {
+			if !strings.HasSuffix(text, ":") {
+				return "", "", false
+			}
+			colon = len(text) - 1
+		}
+		key = strings.TrimSpace(text[:colon])
+		rest = text[colon+1:]
+	}
+	if key == "" || strings.ContainsAny(key, "[]{}") {
+		return "", "", false
+	}
+	return key, strings.TrimSpace(stripComment(rest)), true
+}
+
+// TOML
+
+// parseTOML decodes a TOML document into a map.
+func parseTOML(data []byte) (map[string]interface{}, error) {
+	var values map[string]interface{}
+	if _, err := toml.Decode(string(data), &values); err != nil {
+		return nil, err
+	}
+	return decodedTOML(values).(map[string]interface{}), nil
+}
+
+// decodedTOML converts what the TOML decoder returns into the values the rest
+// of the package uses. Dates and times are kept as text, the way they would
+// be written.
+func decodedTOML(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = decodedTOML(item)
+		}
+		return v
+	case []map[string]interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = decodedTOML(item)
+		}
+		return list
+	case []interface{}:
+		for i, item := range v {
+			v[i] = decodedTOML(item)
+		}
+		return v
+	case time.Time:
+		// The decoder marks local dates and times with a location of
+		// their own.
+		switch v.Location().String() {
+		case "date-local":
+			return v.Format("2006-01-02")
+		case "datetime-local":
+			return v.Format("2006-01-02T15:04:05.999999999")
+		case "time-local":
+			return v.Format("15:04:05.999999999")
+		}
+		return v.Format(time.RFC3339Nano)
+	}
+	return normaliseValue(value)
+}
+
+func unquoteKey(key string) string {
+	if len(key) >= 2 && (key[0] == '"' || key[0] == '\'') && key[len(key)-1] == key[0] {
+		return key[1 : len(key)-1]
+	}
+	return key
+}
+
+// Shared helpers
+
+// eachOutsideQuotes calls fn with the index of every byte in s that is not
+// part of a quoted string. Quotes only open at the start of a value, so
+// apostrophes in plain text are left alone. Iteration stops when fn returns
+// false.
+func eachOutsideQuotes(s string, fn func(i int) bool) {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		if (c == '"' || c == '\'') && (i == 0 || strings.IndexByte(" \t[{,:=.", s[i-1]) >= 0) {
+			quote = c
+			continue
+		}
+		if !fn(i) {
+			return
+		}
+	}
+}
+
+// stripComment removes a trailing `#` comment that is not inside quotes.
+func stripComment(s string) string {
+	end := len(s)
+	eachOutsideQuotes(s, func(i int) bool {
+		if s[i] == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t') {
+			end = i
+			return false
+		}
+		return true
+	})
+	return s[:end]
+}
+
+// balanced reports whether every bracket opened in s is also closed.
+func balanced(s string) bool {
+	depth := 0
+	eachOutsideQuotes(s, func(i int) bool {
+		switch s[i] {
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+		}
+		return true
+	})
+	return depth <= 0
+}
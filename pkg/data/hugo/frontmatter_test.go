@@ -0,0 +1,315 @@
+package hugo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		format  Format
+		fm      string
+		body    string
+	}{
+		{"yaml", "---\ntitle: A\n---\nbody\n", YAML, "---\ntitle: A\n---\n", "body\n"},
+		{"toml", "+++\ntitle = \"A\"\n+++\nbody\n", TOML, "+++\ntitle = \"A\"\n+++\n", "body\n"},
+		{"json", "{\"title\": \"A\"}\nbody\n", JSON, "{\"title\": \"A\"}", "\nbody\n"},
+		{"org", "#+title: A\n#+date: 2021-01-01\nbody\n", Org, "#+title: A\n#+date: 2021-01-01\n", "body\n"},
+		{"crlf", "---\r\ntitle: A\r\n---\r\nbody", YAML, "---\r\ntitle: A\r\n---\r\n", "body"},
+		{"bom", "\ufeff---\ntitle: A\n---\n", YAML, "---\ntitle: A\n---\n", ""},
+		{"no front matter", "just text\n", NoFrontMatter, "", "just text\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			format, fm, body, err := splitFrontMatter([]byte(test.content))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if format != test.format || string(fm) != test.fm || string(body) != test.body {
+				t.Errorf("got %s %q %q, want %s %q %q", format, fm, body, test.format, test.fm, test.body)
+			}
+		})
+	}
+}
+
+func TestSplitFrontMatterErrors(t *testing.T) {
+	for _, content := range []string{
+		"---\ntitle: A\n",
+		"+++\ntitle = \"A\"\n",
+		"{\"title\": \"A\"\n",
+	} {
+		if _, _, _, err := splitFrontMatter([]byte(content)); err == nil {
+			t.Errorf("%q: expected an error", content)
+		}
+	}
+}
+
+func TestParseFrontMatter(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		fm     string
+		want   map[string]interface{}
+	}{
+		{
+			name:   "yaml scalars",
+			format: YAML,
+			fm: "---\ntitle: \"Hello: World\" # comment\ndate: 2021-01-15T10:00:00Z\n" +
+				"draft: true\nweight: 3\nratio: 0.5\nempty: ~\nsingle: 'it''s'\n---\n",
+			want: map[string]interface{}{
+				"title": "Hello: World", "date": "2021-01-15T10:00:00Z", "draft": true,
+				"weight": 3, "ratio": 0.5, "empty": nil, "single": "it's",
+			},
+		},
+		{
+			name:   "yaml nested",
+			format: YAML,
+			fm: "---\nTitle: A\nparams:\n  author: me\n  social:\n    github: sudosays\n" +
+				"tags:\n- go\n- \"hugo, blogs\"\nmenu:\n  - name: Home\n    weight: 1\n---\n",
+			want: map[string]interface{}{
+				"title": "A",
+				"params": map[string]interface{}{
+					"author": "me",
+					"social": map[string]interface{}{"github": "sudosays"},
+				},
+				"tags": []interface{}{"go", "hugo, blogs"},
+				"menu": []interface{}{map[string]interface{}{"name": "Home", "weight": 1}},
+			},
+		},
+		{
+			name:   "yaml block scalars",
+			format: YAML,
+			fm:     "---\nliteral: |\n  one\n  two\nfolded: >-\n  one\n  two\nnext: x\n---\n",
+			want:   map[string]interface{}{"literal": "one\ntwo\n", "folded": "one two", "next": "x"},
+		},
+		{
+			name:   "yaml quoted keys and flow collections",
+			format: YAML,
+			fm:     "---\n\"odd key\": 1\n'other': 2\ntags: [go, \"a, b\"]\nparams: {a: 1, b: [x]}\n---\n",
+			want: map[string]interface{}{
+				"odd key": 1, "other": 2,
+				"tags":   []interface{}{"go", "a, b"},
+				"params": map[string]interface{}{"a": 1, "b": []interface{}{"x"}},
+			},
+		},
+		{
+			name:   "toml",
+			format: TOML,
+			fm: "+++\ntitle = \"A # not a comment\" # comment\ndate = 2021-02-01\ndraft = false\n" +
+				"weight = 1_000\ntags = [\"a\", 'b']\ncategories = [\n  \"x\",\n  \"y\",\n]\n" +
+				"\"quoted key\" = 1\nsite.name = \"dotted\"\ninline = { a = 1, b = \"two\" }\n" +
+				"[params]\nauthor = \"me\"\n[params.social]\ngithub = \"sudosays\"\n" +
+				"[[menu]]\nname = \"Home\"\n[[menu]]\nname = \"About\"\n+++\n",
+			want: map[string]interface{}{
+				"title": "A # not a comment", "date": "2021-02-01", "draft": false,
+				"weight": 1000, "tags": []interface{}{"a", "b"},
+				"categories": []interface{}{"x", "y"}, "quoted key": 1,
+				"site":   map[string]interface{}{"name": "dotted"},
+				"inline": map[string]interface{}{"a": 1, "b": "two"},
+				"params": map[string]interface{}{
+					"author": "me",
+					"social": map[string]interface{}{"github": "sudosays"},
+				},
+				"menu": []interface{}{
+					map[string]interface{}{"name": "Home"},
+					map[string]interface{}{"name": "About"},
+				},
+			},
+		},
+		{
+			name:   "toml multi-line strings",
+			format: TOML,
+			fm:     "+++\nsummary = \"\"\"\none\ntwo\"\"\"\nraw = '''\nC:\\path'''\n+++\n",
+			want:   map[string]interface{}{"summary": "one\ntwo", "raw": "C:\\path"},
+		},
+		{
+			name:   "json",
+			format: JSON,
+			fm:     "{\"Title\": \"A\", \"draft\": true, \"tags\": [\"a\"], \"params\": {\"x\": 1}}",
+			want: map[string]interface{}{
+				"title": "A", "draft": true, "tags": []interface{}{"a"},
+				"params": map[string]interface{}{"x": float64(1)},
+			},
+		},
+		{
+			name:   "org",
+			format: Org,
+			fm:     "#+TITLE: Org post\n#+date: <2021-03-04 Thu>\n#+tags: emacs org\n#+keywords[]: a b\n",
+			want: map[string]interface{}{
+				"title": "Org post", "date": "<2021-03-04 Thu>",
+				"tags": []interface{}{"emacs", "org"}, "keywords": []interface{}{"a", "b"},
+			},
+		},
+		{
+			name:   "none",
+			format: NoFrontMatter,
+			want:   map[string]interface{}{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseFrontMatter(test.format, []byte(test.fm))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %#v\nwant %#v", got, test.want)
+			}
+		})
+	}
+}
+
+// TestParseYAML covers YAML that is valid but easy to get wrong.
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want map[string]interface{}
+	}{
+		{
+			name: "block scalar keeps blank lines and hashes",
+			yaml: "description: |\n  # Heading\n\n  text\n\n  more\nnext: x\n",
+			want: map[string]interface{}{"description": "# Heading\n\ntext\n\nmore\n", "next": "x"},
+		},
+		{
+			name: "block scalar keeps inner indentation",
+			yaml: "code: |-\n  if x:\n      y\n  done\n",
+			want: map[string]interface{}{"code": "if x:\n    y\ndone"},
+		},
+		{
+			name: "folded scalar keeps paragraphs",
+			yaml: "summary: >\n  one\n  two\n\n  three\n",
+			want: map[string]interface{}{"summary": "one two\nthree\n"},
+		},
+		{
+			name: "multi-line plain scalar",
+			yaml: "title: a title that\n  runs over\n  three lines\n",
+			want: map[string]interface{}{"title": "a title that runs over three lines"},
+		},
+		{
+			name: "multi-line double-quoted scalar",
+			yaml: "title: \"a quoted\n  title\"\ndescription: 'single\n  quoted'\n",
+			want: map[string]interface{}{"title": "a quoted title", "description": "single quoted"},
+		},
+		{
+			name: "titles are kept as written",
+			yaml: "title: 1e3\nslug: 2021\nsummary: true\nweight: 1e3\ncount: 0x10\n",
+			want: map[string]interface{}{"title": "1e3", "slug": "2021", "summary": "true", "weight": float64(1000), "count": 16},
+		},
+		{
+			name: "dates are kept as written",
+			yaml: "date: 2021-01-15\nlastmod: 2021-01-15 10:00:00\n",
+			want: map[string]interface{}{"date": "2021-01-15", "lastmod": "2021-01-15 10:00:00"},
+		},
+		{
+			name: "empty",
+			yaml: "# only a comment\n",
+			want: map[string]interface{}{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseYAML([]byte(test.yaml))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %#v\nwant %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseFrontMatterErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		fm     string
+	}{
+		{"yaml without key", YAML, "---\njust text\n---\n"},
+		{"yaml bad indentation", YAML, "---\na: 1\n  b: 2\n---\n"},
+		{"yaml unterminated string", YAML, "---\ntitle: \"open\n---\n"},
+		{"yaml unterminated single quote", YAML, "---\ntitle: 'open\n---\n"},
+		{"yaml unterminated list", YAML, "---\ntags: [a, b\n---\n"},
+		{"yaml unterminated map", YAML, "---\nparams: {a: 1\n---\n"},
+		{"yaml bad flow map", YAML, "---\nparams: {a: 1]\n---\n"},
+		{"yaml tab indentation", YAML, "---\nparams:\n\tauthor: me\n---\n"},
+		{"yaml not a map", YAML, "---\n- a\n- b\n---\n"},
+		{"yaml bad list item", YAML, "---\ntags:\n  - \"open\n---\n"},
+		{"yaml unterminated key", YAML, "---\n\"key: 1\n---\n"},
+		{"toml without value", TOML, "+++\ntitle\n+++\n"},
+		{"toml unterminated string", TOML, "+++\ntitle = \"open\n+++\n"},
+		{"toml unterminated array", TOML, "+++\ntags = [\"a\"\n+++\n"},
+		{"toml unterminated table", TOML, "+++\nparams = { a = 1\n+++\n"},
+		{"toml bad inline table", TOML, "+++\nparams = { a }\n+++\n"},
+		{"toml key is not a table", TOML, "+++\ntitle = \"A\"\n[title]\nx = 1\n+++\n"},
+		{"toml dotted key is not a table", TOML, "+++\ntitle = \"A\"\ntitle.x = 1\n+++\n"},
+		{"json", JSON, "{\"title\": }"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := parseFrontMatter(test.format, []byte(test.fm)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+// TestParseTruncatedFrontMatter feeds every prefix of some front matter to the
+// parsers, which may fail but must not panic.
+func TestParseTruncatedFrontMatter(t *testing.T) {
+	yaml := "title: \"A\"\nparams:\n  list:\n  - name: x\n    v: [1, {a: 'b'}]\ntext: |\n  line\n"
+	toml := "title = \"A\"\ntags = [\n\"a\",\n]\n[params.x]\ns = \"\"\"\nml\"\"\"\n[[menu]]\nt = { a = [1] }\n"
+	org := "#+title: A\n#+tags[]: a b\n"
+	for i := 0; i <= len(yaml); i++ {
+		parseYAML([]byte(yaml[:i]))
+	}
+	for i := 0; i <= len(toml); i++ {
+		parseTOML([]byte(toml[:i]))
+	}
+	for i := 0; i <= len(org); i++ {
+		parseOrg([]byte(org[:i]))
+	}
+}
+
+func TestReadPost(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "content/blog/post.md", "---\ntitle: A post\ndate: 2021-01-15\n"+
+		"draft: true\ntags: [go]\nauthor: me\n---\nbody\n")
+	blog := Blog{Path: dir, Config: SiteConfig{ContentDir: defaultContentDir}}
+
+	post, err := blog.readPost("content/blog/post.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Post{
+		Path:    "content/blog/post.md",
+		Title:   "A post",
+		Date:    "2021-01-15T00:00:00Z",
+		Draft:   true,
+		Tags:    []string{"go"},
+		Params:  map[string]interface{}{"author": "me"},
+		Section: "blog",
+		Format:  YAML,
+	}
+	if !reflect.DeepEqual(post, want) {
+		t.Errorf("got %#v\nwant %#v", post, want)
+	}
+}
+
+// writeFile creates a file below dir, along with its directories.
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	file := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,128 +2,485 @@ package hugo
 
 import (
 	"encoding/csv"
+	"errors"
 	"fmt"
-	"io"
-	"log"
+	"io/ioutil"
 	"os"
-	"os/exec"
 	"path"
+	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/sudosays/hydra/pkg/data/index"
 )
 
-// A Blog contains all the data of a Hugo blog. The Path represents the
-// working directory for the site.
+// A Blog contains all the data of a Hugo blog. The Path is the absolute path
+// of the site, which the paths of posts are relative to.
 type Blog struct {
 	Title, Path string
+	// Config holds the settings of the site, from its config files.
+	Config      SiteConfig
 	Posts       []Post
+	useHugoList bool
+	// index is the full-text index used by Search. It is kept up to date as
+	// the posts are reloaded.
+	index *index.Index
+	// byPath maps the path of every post to its position in Posts, and
+	// modTimes holds the modification time of every post file when it was
+	// last read, so that single posts can be reloaded.
+	byPath   map[string]int
+	modTimes map[string]time.Time
 }
 
 // A Post contains all the metadata related to a hugo post, but not the content
-// of the post itself
+// of the post itself. Dates are kept as RFC3339 strings where possible, and
+// any front matter keys without a field of their own end up in Params with
+// lowercased keys.
+//
+// A post that is a leaf bundle has Bundle set, its Path is the index file of
+// the bundle and Resources lists the other files in the bundle directory,
+// relative to that directory.
 type Post struct {
 	Title, Date, Path string
+	Section           string
 	Draft             bool
+	Bundle            bool
+	Resources         []string
+
+	Tags, Categories, Series []string
+	Description, Summary     string
+	Slug                     string
+	Weight                   int
+	Aliases                  []string
+
+	PublishDate, ExpiryDate, Lastmod string
+
+	Params map[string]interface{}
+	Format Format
+}
+
+// Load takes a path to a hugo site working directory and returns a Blog. The
+// posts are read directly from the front matter of the files in the content
+// directory, so the Hugo binary is not needed.
+func Load(path string) (Blog, error) {
+	blog := Blog{Path: path}
+	err := blog.load()
+	return blog, err
+}
+
+// LoadWithHugo works like Load, but lists the posts using `hugo list all`
+// instead of parsing the content directory.
+func LoadWithHugo(path string) (Blog, error) {
+	blog := Blog{Path: path, useHugoList: true}
+	err := blog.load()
+	return blog, err
 }
 
-func check(err error) {
+func (blog *Blog) load() error {
+	abs, err := filepath.Abs(blog.Path)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	blog.Path = abs
+	if err := blog.readConfig(); err != nil {
+		return err
+	}
+	blog.openIndex()
+	return blog.reload()
 }
 
-func loadConfig() string {
-	s := ""
-	hugoConfigCmd := exec.Command("hugo", "config")
-	hugoConfig, err := hugoConfigCmd.Output()
-	s = string(hugoConfig)
-	check(err)
+// readConfig reads the configuration of the site. The title of the blog is
+// the title of the site, or the name of its directory if it has none.
+func (blog *Blog) readConfig() error {
+	config, err := readSiteConfig(blog.Path)
+	if err != nil {
+		return err
+	}
+	blog.Config = config
+	blog.Title = config.Title
+	if blog.Title == "" {
+		blog.Title = filepath.Base(blog.Path)
+	}
+	return nil
+}
+
+// reload replaces the posts of the blog with a fresh listing of the site. After
+// the blog is loaded only the posts that are created, deleted or edited are
+// read again, see refreshPost and forgetPost.
+func (blog *Blog) reload() error {
+	var posts []Post
+	var err error
+	if blog.useHugoList {
+		posts, err = blog.listPosts()
+	} else {
+		posts, err = blog.loadPosts()
+	}
+	if err != nil {
+		return err
+	}
+	blog.Posts = posts
+	blog.indexPaths()
+	blog.modTimes = make(map[string]time.Time, len(posts))
+	for _, post := range posts {
+		blog.noteModTime(post.Path)
+	}
+	return blog.updateIndex()
+}
+
+// indexPaths records the position of every post in Posts.
+func (blog *Blog) indexPaths() {
+	blog.byPath = make(map[string]int, len(blog.Posts))
+	for i, post := range blog.Posts {
+		blog.byPath[post.Path] = i
+	}
+}
+
+// noteModTime records the modification time of the file of a post, as it was
+// read.
+func (blog *Blog) noteModTime(relPath string) {
+	if info, err := os.Stat(filepath.Join(blog.Path, relPath)); err == nil {
+		blog.modTimes[relPath] = info.ModTime()
+	}
+}
+
+// FindPost looks up a post by its path relative to the site.
+func (blog Blog) FindPost(relPath string) (Post, error) {
+	if i, ok := blog.byPath[relPath]; ok {
+		return blog.Posts[i], nil
+	}
+	return Post{}, fmt.Errorf("%w: %s", ErrPostNotFound, relPath)
+}
 
-	return s
+// Refresh reads a post again if its file changed since it was last read, for
+// example after it was opened in an editor. A post whose file was removed is
+// dropped from the list of posts.
+func (blog *Blog) Refresh(relPath string) error {
+	info, err := os.Stat(filepath.Join(blog.Path, relPath))
+	if os.IsNotExist(err) {
+		return blog.forgetPost(relPath)
+	}
+	if err != nil {
+		return err
+	}
+	if modTime, ok := blog.modTimes[relPath]; ok && modTime.Equal(info.ModTime()) {
+		return nil
+	}
+	return blog.refreshPost(relPath)
 }
 
-// Load takes a path to a hugo site working directory and returns a Blog.
-func Load(path string) Blog {
-	os.Chdir(path)
-	return Blog{Title: "blog", Path: path, Posts: loadPosts()}
+// defaultSection is the section new posts go in when none is given.
+const defaultSection = "blog"
+
+// PostOptions describe a post to create with NewPost.
+type PostOptions struct {
+	Title     string
+	Extension string
+	// Section is the section (a directory below content) to create the post
+	// in, "blog" if it is empty.
+	Section string
+	// Kind is the archetype to create the post from. If it is empty Hugo picks
+	// the archetype named after the section, or the default one.
+	Kind string
+	// Bundle creates the post as a leaf bundle, `<slug>/index.<ext>`, so that
+	// images and other resources can be kept next to it.
+	Bundle bool
+	// Slug controls how the file name is made from the title.
+	Slug SlugOptions
 }
 
-// NewPost takes a title string and creates a new blog post before returning
-// the path to the created file. Important: for now, the default is to make a
-// new blog post in `$SITE_PATH/content/blog`
-func (blog *Blog) NewPost(title string, extension string) string {
-	log.Printf("Attempting to add post with title: %s, and extension %s\n", title, extension)
-	os.Chdir(blog.Path)
-	log.Printf("Path changed to: %s\n", blog.Path)
+// NewPost creates a new post with `hugo new` and returns it. The title of the
+// post is set to opts.Title, since Hugo makes one up from the file name. It
+// fails with ErrPostExists rather than touch an existing file.
+func (blog *Blog) NewPost(opts PostOptions) (*Post, error) {
+	if strings.TrimSpace(opts.Title) == "" {
+		return nil, errors.New("a post needs a title")
+	}
+	section := strings.Trim(path.Clean("/"+opts.Section), "/")
+	if section == "" {
+		section = defaultSection
+	}
+	filePath, err := blog.postFilePath(section, opts.Title, opts.Extension, opts.Bundle, opts.Slug)
+	if err != nil {
+		return nil, err
+	}
 
-	filename := strings.ToLower(title)
-	filename = strings.TrimSpace(filename)
-	filename = strings.ReplaceAll(filename, " ", "-")
-	filename = strings.ReplaceAll(filename, ":", "")
-	filePath := fmt.Sprintf("%s/%s.%s", "blog", filename, extension)
-	log.Printf("Post file path is: %s\n", filePath)
+	args := []string{"new", filePath}
+	if opts.Kind != "" {
+		kinds, err := blog.Archetypes()
+		if err != nil {
+			return nil, err
+		}
+		if !contains(kinds, opts.Kind) {
+			return nil, fmt.Errorf("the site has no archetype called '%s'", opts.Kind)
+		}
+		args = append(args, "--kind", opts.Kind)
+	}
+	output, err := runHugo(blog.Path, args...)
+	if err != nil {
+		var hugoErr *HugoError
+		if errors.As(err, &hugoErr) && strings.Contains(hugoErr.Output, "already exists") {
+			return nil, fmt.Errorf("%w: %s", ErrPostExists, path.Join(blog.contentDir(), filePath))
+		}
+		return nil, err
+	}
 
-	newPostCmd := exec.Command("hugo", "new", filePath)
-	postPathRaw, err := newPostCmd.Output()
-	postPath := string(postPathRaw)
-	postPath = strings.Split(postPath, " ")[0]
-	log.Printf("Post path from command result is: %s\n", postPath)
-	check(err)
+	relPath := blog.createdPath(output)
+	if relPath == "" {
+		relPath = path.Join(blog.contentDir(), filePath)
+	}
+	post, err := blog.readPost(relPath)
+	if err != nil {
+		return nil, err
+	}
+	if post.Title != opts.Title {
+		post.Title = opts.Title
+		if _, err := blog.UpdatePost(post); err != nil {
+			return nil, err
+		}
+		return &post, nil
+	}
+	return &post, blog.refreshPost(relPath)
+}
 
-	blog.Posts = loadPosts()
+// createdPath finds the file that `hugo new` reports it created in its output,
+// `Content "/site/content/blog/post.md" created` or, for older versions of
+// Hugo, `/site/content/blog/post.md created`. The path is returned relative to
+// the site, or empty if it cannot be found.
+func (blog Blog) createdPath(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasSuffix(line, " created") {
+			continue
+		}
+		created := strings.TrimSuffix(line, " created")
+		created = strings.TrimPrefix(created, "Content ")
+		created = strings.Trim(created, `"`)
+		if !filepath.IsAbs(created) {
+			created = filepath.Join(blog.Path, created)
+		}
+		rel, err := filepath.Rel(resolvePath(blog.Path), resolvePath(created))
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return ""
+		}
+		return filepath.ToSlash(rel)
+	}
+	return ""
+}
 
-	return blog.Posts[0].Path
+func resolvePath(p string) string {
+	if resolved, err := filepath.EvalSymlinks(p); err == nil {
+		return resolved
+	}
+	return filepath.Clean(p)
 }
 
-func loadPosts() []Post {
-	/// TODO find a way to partially reload the posts in a blog?
-	/// Times when effective:
-	/// 	a) deleting a post
-	/// 	b) creating a post
+// listPosts uses `hugo list all` to find the posts of the site.
+func (blog Blog) listPosts() ([]Post, error) {
+	rawPostList, err := runHugo(blog.Path, "list", "all")
+	if err != nil {
+		return nil, err
+	}
+	records, err := csv.NewReader(strings.NewReader(rawPostList)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("reading the output of hugo list: %w", err)
+	}
+
 	var posts []Post
-	hugoListCmd := exec.Command("hugo", "list", "all")
-	rawPostList, err := hugoListCmd.Output()
-	csvReader := csv.NewReader(strings.NewReader(string(rawPostList)))
-	isHeader := true
-	for {
-		record, err := csvReader.Read()
-		if err == io.EOF {
-			break
-		} else {
-			if isHeader {
-				isHeader = !isHeader
-				continue
-			} else {
-				post := Post{Path: record[0],
-					Date:  record[3],
-					Title: record[2],
-					Draft: (record[6] == "true"),
-				}
-				posts = append(posts, post)
-			}
+	for i, record := range records {
+		// Skip the header.
+		if i == 0 {
+			continue
+		}
+		if len(record) < 7 {
+			return nil, fmt.Errorf("unexpected line in the output of hugo list: %s", strings.Join(record, ","))
 		}
+		post := Post{Path: record[0],
+			Date:    record[3],
+			Title:   record[2],
+			Draft:   (record[6] == "true"),
+			Section: blog.sectionOf(record[0]),
+		}
+		if err := post.readBundle(blog.Path); err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
 	}
-	check(err)
-	return posts
+	return posts, nil
 }
 
-// Synchronise removes all the files in a blog's public directory and then
-// builds the site with Hugo.
-func (blog Blog) Synchronise() {
-	os.Chdir(blog.Path)
+// ContentDir returns the directory that holds the content of the site, as set
+// by contentDir in the site config.
+func (blog Blog) ContentDir() string {
+	return filepath.Join(blog.Path, blog.contentDir())
+}
 
-	buildCmd := exec.Command("hugo")
+// PublishDir returns the directory the site is built into, as set by
+// publishDir in the site config.
+func (blog Blog) PublishDir() string {
+	return filepath.Join(blog.Path, siteDir(blog.Path, blog.Config.PublishDir))
+}
 
-	err := buildCmd.Run()
-	check(err)
+// contentDir returns the content directory relative to the site, with forward
+// slashes like the paths of posts.
+func (blog Blog) contentDir() string {
+	return siteDir(blog.Path, blog.Config.ContentDir)
 }
 
-// DeletePost removes the post file from the site's content/section directory.
-// Warning: this method is destructive and should use user confirmation.
-func (blog *Blog) DeletePost(deletePath string) error {
+// siteDir makes a directory from the site config relative to the site. Hugo
+// allows them to be absolute.
+func siteDir(sitePath, dir string) string {
+	if filepath.IsAbs(dir) {
+		if rel, err := filepath.Rel(sitePath, dir); err == nil {
+			dir = rel
+		}
+	}
+	return path.Clean(filepath.ToSlash(dir))
+}
 
-	os.Chdir(blog.Path)
-	postPath := path.Join(blog.Path, deletePath)
-	err := os.Remove(postPath)
-	blog.Posts = loadPosts()
+// Synchronise removes all the files in a blog's publish directory and then
+// builds the site with Hugo. The output of Hugo is included in the error if the
+// build fails.
+func (blog Blog) Synchronise() error {
+	return blog.Build(blog.PublishDir())
+}
+
+// Build builds the site with Hugo into dir, removing everything else in it.
+func (blog Blog) Build(dir string) error {
+	_, err := runHugo(blog.Path, "--cleanDestinationDir", "--destination", dir)
 	return err
 }
+
+// UpdatePost writes the front matter of post back to its file. Only the keys
+// the caller changed since the post was loaded are written, and only if the
+// file does not already have the new value, so the body, comments, key order,
+// unknown keys and edits made to other keys outside hydra are left as they
+// were. It returns the keys that were changed.
+func (blog *Blog) UpdatePost(post Post) ([]string, error) {
+	filePath := filepath.Join(blog.Path, post.Path)
+	info, err := os.Stat(filePath)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrPostNotFound, post.Path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	format, fm, body, err := splitFrontMatter(content)
+	if err != nil {
+		return nil, err
+	}
+	values, err := parseFrontMatter(format, fm)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := postChanges(newPost(post.Path, values), post)
+	if loaded, ok := blog.loadedPost(post.Path); ok {
+		changes = onlyKeys(changes, postChanges(loaded, post))
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	if format == NoFrontMatter {
+		format, fm = YAML, []byte("---\n---\n")
+	}
+
+	var changed []string
+	for _, c := range changes {
+		fm, err = setFrontMatter(format, fm, c.key, c.value)
+		if err != nil {
+			return nil, err
+		}
+		changed = append(changed, c.key)
+	}
+
+	err = ioutil.WriteFile(filePath, append(fm, body...), info.Mode())
+	if err != nil {
+		return nil, err
+	}
+	return changed, blog.refreshPost(post.Path)
+}
+
+// loadedPost returns a post as it was when it was last read. Posts listed with
+// `hugo list` lack most of their front matter, so they are not used.
+func (blog Blog) loadedPost(relPath string) (Post, bool) {
+	i, ok := blog.byPath[relPath]
+	if !ok || blog.useHugoList {
+		return Post{}, false
+	}
+	return blog.Posts[i], true
+}
+
+// Publish marks a draft as published. If setDate is true the date of the post
+// (and its publishDate, if it has one) is set to the current time. The post is
+// read from its file first, so that only the draft status and dates are
+// written. It returns the front matter keys that were changed.
+func (blog *Blog) Publish(post Post, setDate bool) ([]string, error) {
+	post, err := blog.readPost(post.Path)
+	if err != nil {
+		return nil, err
+	}
+	post.Draft = false
+	if setDate {
+		now := time.Now().Format(time.RFC3339)
+		post.Date = now
+		if post.PublishDate != "" {
+			post.PublishDate = now
+		}
+	}
+	return blog.UpdatePost(post)
+}
+
+// Unpublish turns a post back into a draft. Like Publish, only the draft
+// status is written. It returns the front matter keys that were changed.
+func (blog *Blog) Unpublish(post Post) ([]string, error) {
+	post, err := blog.readPost(post.Path)
+	if err != nil {
+		return nil, err
+	}
+	post.Draft = true
+	return blog.UpdatePost(post)
+}
+
+// refreshPost re-reads a single post from disk and puts it back in its place
+// in the list of posts. The search index is only updated in memory, see
+// SaveIndex.
+func (blog *Blog) refreshPost(relPath string) error {
+	if blog.useHugoList {
+		return blog.reload()
+	}
+	post, err := blog.readPost(relPath)
+	if err != nil {
+		return err
+	}
+	if i, ok := blog.byPath[relPath]; ok {
+		blog.Posts[i] = post
+	} else {
+		blog.Posts = append(blog.Posts, post)
+	}
+	sortPosts(blog.Posts)
+	blog.indexPaths()
+	blog.noteModTime(relPath)
+	return blog.indexPost(relPath)
+}
+
+// forgetPost drops a post whose file is gone from the list of posts.
+func (blog *Blog) forgetPost(relPath string) error {
+	if blog.useHugoList {
+		return blog.reload()
+	}
+	if i, ok := blog.byPath[relPath]; ok {
+		posts := make([]Post, 0, len(blog.Posts)-1)
+		posts = append(posts, blog.Posts[:i]...)
+		blog.Posts = append(posts, blog.Posts[i+1:]...)
+		blog.indexPaths()
+	}
+	delete(blog.modTimes, relPath)
+	if blog.index != nil {
+		blog.index.Remove(relPath)
+	}
+	return nil
+}
@@ -0,0 +1,366 @@
+package hugo
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/sudosays/hydra/pkg/data/index"
+)
+
+// fakeHugoNew stands in for `hugo new`: it creates the file it is given below
+// content, titled after the file name like Hugo's default archetype, and
+// reports it the way Hugo does.
+const fakeHugoNew = `
+if [ "$1" != new ]; then echo "unexpected: $*" >&2; exit 2; fi
+f="content/$2"
+if [ -e "$f" ]; then echo "Error: $PWD/$f already exists" >&2; exit 1; fi
+mkdir -p "$(dirname "$f")"
+printf -- '---\ntitle: "%s"\ndate: 2021-06-01T10:00:00Z\ndraft: true\n---\n' "$(basename "$2" .md)" > "$f"
+echo "Content \"$PWD/$f\" created"
+`
+
+// fakeHugo puts a shell script called hugo first in $PATH for the rest of the
+// test, and returns the file its arguments are logged to.
+func fakeHugo(t *testing.T, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake hugo is a shell script")
+	}
+	dir := t.TempDir()
+	calls := filepath.Join(dir, "calls")
+	script = "#!/bin/sh\necho \"$*\" >> " + calls + "\n" + script
+	if err := ioutil.WriteFile(filepath.Join(dir, "hugo"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+	t.Cleanup(func() { os.Setenv("PATH", path) })
+	return calls
+}
+
+// loadTestBlog loads the site in dir without a search index, so that tests
+// do not write to the user's cache.
+func loadTestBlog(t *testing.T, dir string) *Blog {
+	t.Helper()
+	blog := &Blog{Path: dir}
+	if err := blog.readConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if err := blog.reload(); err != nil {
+		t.Fatal(err)
+	}
+	return blog
+}
+
+func readFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	content, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestPublishKeepsExternalEdits(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "content/blog/post.md", "---\ntitle: Old\ntags: [a]\ndraft: true\n---\nbody\n")
+	blog := loadTestBlog(t, dir)
+	post, err := blog.FindPost("content/blog/post.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Edited in another editor after hydra read it.
+	writeFile(t, dir, "content/blog/post.md", "---\ntitle: New\ntags: [a, b]\ndraft: true\n---\nnew body\n")
+
+	changed, err := blog.Publish(post, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(changed, ",") != "draft" {
+		t.Errorf("changed %v, want only draft", changed)
+	}
+	want := "---\ntitle: New\ntags: [a, b]\ndraft: false\n---\nnew body\n"
+	if got := readFile(t, dir, "content/blog/post.md"); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	if _, err := blog.Unpublish(post); err != nil {
+		t.Fatal(err)
+	}
+	want = "---\ntitle: New\ntags: [a, b]\ndraft: true\n---\nnew body\n"
+	if got := readFile(t, dir, "content/blog/post.md"); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestUpdatePostOnlyWritesChangedKeys(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "content/blog/post.md", "+++\ntitle = \"Old\"\ntags = [\"a\"]\n+++\nbody\n")
+	blog := loadTestBlog(t, dir)
+	post, err := blog.FindPost("content/blog/post.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, dir, "content/blog/post.md", "+++\ntitle = \"Edited\"\ntags = [\"a\"]\n+++\nbody\n")
+
+	post.SetTerms("tags", []string{"b"})
+	changed, err := blog.UpdatePost(post)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(changed, ",") != "tags" {
+		t.Errorf("changed %v, want only tags", changed)
+	}
+	want := "+++\ntitle = \"Edited\"\ntags = [\"b\"]\n+++\nbody\n"
+	if got := readFile(t, dir, "content/blog/post.md"); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if post, _ := blog.FindPost("content/blog/post.md"); post.Title != "Edited" {
+		t.Errorf("the post was not read again, its title is %q", post.Title)
+	}
+}
+
+func TestNewPost(t *testing.T) {
+	calls := fakeHugo(t, fakeHugoNew)
+	dir := t.TempDir()
+	// A newer post sorts before the new one.
+	writeFile(t, dir, "content/blog/later.md", "---\ntitle: Later\ndate: 2030-01-01\n---\n")
+	blog := loadTestBlog(t, dir)
+
+	post, err := blog.NewPost(PostOptions{Title: "My New Post", Extension: "md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Path != "content/blog/my-new-post.md" || post.Title != "My New Post" ||
+		post.Date != "2021-06-01T10:00:00Z" || !post.Draft || post.Section != "blog" {
+		t.Errorf("NewPost returned %+v", post)
+	}
+	if got := readFile(t, dir, "content/blog/my-new-post.md"); !strings.Contains(got, "title: \"My New Post\"\n") {
+		t.Errorf("the title was not written to the file:\n%s", got)
+	}
+	if _, err := blog.FindPost(post.Path); err != nil || len(blog.Posts) != 2 {
+		t.Errorf("the new post was not added to the posts: %v", err)
+	}
+	if got := readFile(t, calls, ""); got != "new blog/my-new-post.md\n" {
+		t.Errorf("hugo was run as %q", got)
+	}
+}
+
+func TestNewPostOptions(t *testing.T) {
+	calls := fakeHugo(t, `
+if [ "$3" = --kind ]; then set -- "$1" "$2"; fi
+`+fakeHugoNew)
+	dir := t.TempDir()
+	writeFile(t, dir, "archetypes/gallery.md", "---\n---\n")
+	writeFile(t, dir, "content/_index.md", "")
+	blog := loadTestBlog(t, dir)
+
+	post, err := blog.NewPost(PostOptions{Title: "Photos", Extension: "md", Section: "notes", Kind: "gallery", Bundle: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Path != "content/notes/photos/index.md" || !post.Bundle || post.Section != "notes" {
+		t.Errorf("NewPost returned %+v", post)
+	}
+	if got := readFile(t, calls, ""); got != "new notes/photos/index.md --kind gallery\n" {
+		t.Errorf("hugo was run as %q", got)
+	}
+
+	if _, err := blog.NewPost(PostOptions{Title: "Other", Extension: "md", Kind: "missing"}); err == nil {
+		t.Error("a missing archetype was accepted")
+	}
+}
+
+func TestNewPostUsesTheCreatedFile(t *testing.T) {
+	// Hugo may put the post somewhere else than asked, e.g. when the site
+	// sets another content directory.
+	fakeHugo(t, `
+mkdir -p src/blog
+printf -- '---\ntitle: Elsewhere\n---\n' > src/blog/elsewhere.md
+echo "$PWD/src/blog/elsewhere.md created"
+`)
+	dir := t.TempDir()
+	writeFile(t, dir, "hugo.toml", "contentDir = \"src\"\n")
+	writeFile(t, dir, "src/_index.md", "")
+	blog := loadTestBlog(t, dir)
+
+	post, err := blog.NewPost(PostOptions{Title: "Elsewhere", Extension: "md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Path != "src/blog/elsewhere.md" || post.Title != "Elsewhere" {
+		t.Errorf("NewPost returned %+v", post)
+	}
+}
+
+func TestNewPostErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		opts   PostOptions
+		want   error
+	}{
+		{
+			name:   "existing file",
+			script: fakeHugoNew,
+			opts:   PostOptions{Title: "Existing", Extension: "md"},
+			want:   ErrPostExists,
+		},
+		{
+			name:   "hugo reports an existing file",
+			script: `echo "Error: $PWD/content/blog/raced.md already exists" >&2; exit 1`,
+			opts:   PostOptions{Title: "Raced", Extension: "md"},
+			want:   ErrPostExists,
+		},
+		{
+			name:   "no title",
+			script: fakeHugoNew,
+			opts:   PostOptions{Title: "  ", Extension: "md"},
+		},
+		{
+			name:   "hugo fails",
+			script: `echo "Error: something broke" >&2; exit 1`,
+			opts:   PostOptions{Title: "Broken", Extension: "md"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fakeHugo(t, test.script)
+			dir := t.TempDir()
+			writeFile(t, dir, "content/blog/existing.md", "---\ntitle: Old\n---\nkeep\n")
+			blog := loadTestBlog(t, dir)
+
+			post, err := blog.NewPost(test.opts)
+			if err == nil {
+				t.Fatalf("NewPost did not fail, it returned %+v", post)
+			}
+			if test.want != nil && !errors.Is(err, test.want) {
+				t.Errorf("got %v, want %v", err, test.want)
+			}
+			if got := readFile(t, dir, "content/blog/existing.md"); got != "---\ntitle: Old\n---\nkeep\n" {
+				t.Errorf("the existing post was changed:\n%s", got)
+			}
+		})
+	}
+
+	t.Run("hugo fails with its output", func(t *testing.T) {
+		fakeHugo(t, `echo "Error: something broke" >&2; exit 1`)
+		dir := t.TempDir()
+		writeFile(t, dir, "content/_index.md", "")
+		blog := loadTestBlog(t, dir)
+		_, err := blog.NewPost(PostOptions{Title: "Broken", Extension: "md"})
+		var hugoErr *HugoError
+		if !errors.As(err, &hugoErr) || hugoErr.Output != "Error: something broke" {
+			t.Errorf("got %v, want a HugoError with the output of hugo", err)
+		}
+	})
+
+	t.Run("hugo is missing", func(t *testing.T) {
+		path := os.Getenv("PATH")
+		os.Setenv("PATH", t.TempDir())
+		defer os.Setenv("PATH", path)
+		dir := t.TempDir()
+		writeFile(t, dir, "content/_index.md", "")
+		blog := loadTestBlog(t, dir)
+		if _, err := blog.NewPost(PostOptions{Title: "Post", Extension: "md"}); !errors.Is(err, ErrHugoNotFound) {
+			t.Errorf("got %v, want ErrHugoNotFound", err)
+		}
+	})
+}
+
+func TestCreatedPath(t *testing.T) {
+	blog := Blog{Path: "/site"}
+	for output, want := range map[string]string{
+		"Content \"/site/content/blog/a.md\" created\n": "content/blog/a.md",
+		"/site/content/blog/b.md created\n":             "content/blog/b.md",
+		"Content \"content/blog/c.md\" created":         "content/blog/c.md",
+		"Content \"/elsewhere/d.md\" created":           "",
+		"nothing useful":                                "",
+	} {
+		if got := blog.createdPath(output); got != want {
+			t.Errorf("createdPath(%q) = %q, want %q", output, got, want)
+		}
+	}
+}
+
+func TestIndexSavedOncePerBatch(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b", "c"} {
+		writeFile(t, dir, "content/blog/"+name+".md", "---\ntitle: "+name+"\ntags: [old]\n---\n")
+	}
+	blog := loadTestBlog(t, dir)
+	indexFile := filepath.Join(t.TempDir(), "index.gob")
+	blog.index = index.New(indexFile)
+	if err := blog.updateIndex(); err != nil {
+		t.Fatal(err)
+	}
+	saved := readFile(t, indexFile, "")
+
+	for _, post := range append([]Post(nil), blog.Posts...) {
+		post.SetTerms("tags", []string{"renamed"})
+		if _, err := blog.UpdatePost(post); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if readFile(t, indexFile, "") != saved {
+		t.Error("the index was saved while updating the posts")
+	}
+	if results := blog.index.Search("renamed"); len(results) != 3 {
+		t.Errorf("the index in memory has %d posts with the new tag, want 3", len(results))
+	}
+
+	if err := blog.SaveIndex(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := index.Open(indexFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results := reopened.Search("renamed"); len(results) != 3 {
+		t.Errorf("the saved index has %d posts with the new tag, want 3", len(results))
+	}
+}
+
+// captureLog sends the warnings of the package to a buffer for the rest of
+// the test.
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	Logger.SetOutput(&buf)
+	t.Cleanup(func() { Logger.SetOutput(os.Stderr) })
+	return &buf
+}
+
+func TestInvalidPostsGoToLogger(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "content/blog/good.md", "---\ntitle: Good\n---\n")
+	writeFile(t, dir, "content/blog/bad.md", "---\ntitle: Bad\n")
+	logged := captureLog(t)
+
+	blog := loadTestBlog(t, dir)
+	if len(blog.Posts) != 1 || blog.Posts[0].Title != "Good" {
+		t.Errorf("loaded %+v, want only the good post", blog.Posts)
+	}
+	if !strings.Contains(logged.String(), "Skipping content/blog/bad.md") {
+		t.Errorf("the skipped post was not logged: %q", logged)
+	}
+
+	// Posts that break once loaded are reported by FilesChanged instead.
+	logged.Reset()
+	writeFile(t, dir, "content/blog/good.md", "---\ntitle: Broken\n")
+	err := blog.FilesChanged([]string{filepath.Join(dir, "content/blog/good.md")})
+	if err == nil || !strings.Contains(err.Error(), "content/blog/good.md") {
+		t.Errorf("FilesChanged returned %v, want an error naming the post", err)
+	}
+	if logged.Len() != 0 {
+		t.Errorf("FilesChanged logged %q", logged)
+	}
+}
@@ -0,0 +1,128 @@
+package hugo
+
+import (
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// permalinkToken matches the placeholders in a permalink pattern, such as
+// `:year` or `:slug`. Hugo's `:sections[1:]` style slices are not supported,
+// the brackets are matched so that they are dropped.
+var permalinkToken = regexp.MustCompile(`:(\w+)(\[[^\]]*\])?`)
+
+// PostPath returns the path of a post on the site relative to its base URL,
+// e.g. "blog/my-post/". It follows Hugo: a `url` in the front matter is used as
+// it is, otherwise the permalink pattern of the section of the post is filled
+// in, otherwise the path is the directory of the post below content followed
+// by its slug, or its file name (the directory name of a bundle).
+func (blog Blog) PostPath(post Post) string {
+	if url, ok := post.Params["url"].(string); ok && url != "" {
+		return strings.TrimPrefix(url, "/")
+	}
+
+	rel := strings.TrimPrefix(post.Path, blog.contentDir()+"/")
+	dir, name := path.Dir(rel), strings.TrimSuffix(path.Base(rel), path.Ext(rel))
+	if post.Bundle {
+		dir, name = path.Dir(dir), path.Base(dir)
+	}
+	if dir == "." {
+		dir = ""
+	}
+
+	var p string
+	if pattern, ok := blog.Config.Permalinks[strings.ToLower(post.Section)]; ok {
+		p = expandPermalink(pattern, post, dir, name)
+	} else if post.Slug != "" {
+		p = path.Join(dir, post.Slug)
+	} else {
+		p = path.Join(dir, name)
+	}
+
+	p = strings.Trim(p, "/")
+	if blog.Config.UglyURLs && p != "" {
+		p += ".html"
+	} else if p != "" {
+		p += "/"
+	}
+	if !blog.Config.DisablePathToLower {
+		p = strings.ToLower(p)
+	}
+	return p
+}
+
+// Permalink returns the full URL of a post on the published site. It is only
+// a path if the site does not set a baseURL.
+func (blog Blog) Permalink(post Post) string {
+	return JoinURL(blog.Config.BaseURL, blog.PostPath(post))
+}
+
+// JoinURL appends a path, such as one returned by PostPath, to a base URL.
+func JoinURL(base, p string) string {
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(p, "/")
+}
+
+// expandPermalink fills in the placeholders of a permalink pattern for a post
+// in dir, whose file or bundle is called name.
+func expandPermalink(pattern string, post Post, dir, name string) string {
+	date := parseDate(post.Date)
+	slug := urlize(post.Slug)
+	if slug == "" {
+		slug = urlize(post.Title)
+	}
+	slugOrFilename := urlize(post.Slug)
+	if slugOrFilename == "" {
+		slugOrFilename = name
+	}
+
+	return permalinkToken.ReplaceAllStringFunc(pattern, func(token string) string {
+		key := permalinkToken.FindStringSubmatch(token)[1]
+		switch key {
+		case "year":
+			return date.Format("2006")
+		case "month":
+			return date.Format("01")
+		case "monthname":
+			return date.Format("January")
+		case "day":
+			return date.Format("02")
+		case "weekday":
+			return strconv.Itoa(int(date.Weekday()))
+		case "weekdayname":
+			return date.Format("Monday")
+		case "yearday":
+			return strconv.Itoa(date.YearDay())
+		case "section":
+			return post.Section
+		case "sections":
+			return dir
+		case "title":
+			return urlize(post.Title)
+		case "slug":
+			return slug
+		case "slugorfilename", "slugorcontentbasename":
+			return slugOrFilename
+		case "filename", "contentbasename":
+			return name
+		}
+		return token
+	})
+}
+
+// urlize turns text into a URL path segment the way Hugo does for `:title`:
+// spaces become dashes and characters other than letters, digits, dashes,
+// underscores and dots are dropped.
+func urlize(text string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(text) {
+		switch {
+		case unicode.IsSpace(r):
+			b.WriteByte('-')
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '-', r == '_', r == '.':
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,281 @@
+package hugo
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sudosays/hydra/pkg/data/index"
+)
+
+// A SearchResult is a post that matched a search. Index is the position of the
+// post in Blog.Posts. Matches holds the lines that matched the text of the
+// search, and is empty for searches that only filter on fields.
+type SearchResult struct {
+	Index   int
+	Post    Post
+	Matches []Match
+}
+
+// A Match is a line of a post that contains the text searched for. Line counts
+// from 1 at the top of the file, front matter included, and Start and End are
+// the byte offsets of the match in Text.
+type Match struct {
+	Line       int
+	Text       string
+	Start, End int
+}
+
+// A query is a parsed search: the text to look for in the files, and the
+// front matter fields the posts must have. Plain text searches are looked up
+// in the search index, words holds their text.
+type query struct {
+	text   *regexp.Regexp
+	words  string
+	fields []fieldFilter
+}
+
+// wordChars matches the characters index.Tokenize keeps in words.
+const wordChars = `[\p{L}\p{N}]`
+
+type fieldFilter struct {
+	key, value string
+}
+
+// Search finds the posts whose files contain the text of the query and that
+// pass its field filters. Posts that contain every word of the text are found
+// in the search index and ranked, best matches first. A word ending in `*`
+// matches every word that starts with it. Text written as `/regexp/` is
+// matched as a regular expression against every post instead. Field filters
+// look like `tag:go`, `draft:true` or `section:notes`, and can be combined
+// with text, e.g. `tag:go draft:false goroutine`. Taxonomies can be given by
+// their singular or plural name, and values with spaces can be quoted:
+// `title:"hello world"`.
+func (blog Blog) Search(search string) ([]SearchResult, error) {
+	q, err := blog.parseQuery(search)
+	if err != nil {
+		return nil, err
+	}
+
+	order := make([]int, len(blog.Posts))
+	for i := range order {
+		order[i] = i
+	}
+	if q.words != "" && blog.index != nil {
+		order = blog.rank(q.words)
+	}
+
+	var results []SearchResult
+	for _, i := range order {
+		post := blog.Posts[i]
+		if !blog.matchesFields(post, q.fields) {
+			continue
+		}
+		result := SearchResult{Index: i, Post: post}
+		if q.text != nil {
+			content, err := ioutil.ReadFile(filepath.Join(blog.Path, post.Path))
+			if err != nil {
+				return nil, err
+			}
+			result.Matches = searchLines(content, q.text)
+			if len(result.Matches) == 0 {
+				continue
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// parseQuery splits a search into field filters and the text to look for.
+func (blog Blog) parseQuery(search string) (query, error) {
+	q := query{}
+	var words []string
+	for _, word := range splitQuery(search) {
+		if i := strings.Index(word, ":"); i > 0 && blog.isSearchField(word[:i]) {
+			q.fields = append(q.fields, fieldFilter{
+				key:   strings.ToLower(word[:i]),
+				value: strings.Trim(word[i+1:], `"`),
+			})
+			continue
+		}
+		words = append(words, strings.Trim(word, `"`))
+	}
+
+	text := strings.Join(words, " ")
+	if text == "" && len(q.fields) == 0 {
+		return q, fmt.Errorf("nothing to search for")
+	}
+	if len(text) > 1 && strings.HasPrefix(text, "/") && strings.HasSuffix(text, "/") {
+		re, err := regexp.Compile(text[1 : len(text)-1])
+		if err != nil {
+			return q, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		q.text = re
+	} else if text != "" {
+		q.text = wordsRegexp(text)
+		// The index only holds words, so text with nothing but punctuation
+		// is looked for in every post.
+		if len(index.Tokenize(text)) > 0 {
+			q.words = text
+		}
+	}
+	return q, nil
+}
+
+// rank returns the indices into Blog.Posts of the posts that contain every
+// word, best matches first.
+func (blog Blog) rank(words string) []int {
+	var order []int
+	for _, result := range blog.index.Search(words) {
+		if i, ok := blog.byPath[result.ID]; ok {
+			order = append(order, i)
+		}
+	}
+	return order
+}
+
+// wordsRegexp matches any of the words of a plain text search as whole words,
+// the way the index finds them, so the matching lines can be shown.
+func wordsRegexp(text string) *regexp.Regexp {
+	var alternatives []string
+	for _, word := range strings.Fields(text) {
+		prefix := strings.HasSuffix(word, "*")
+		for _, token := range index.Tokenize(strings.TrimSuffix(word, "*")) {
+			alternative := regexp.QuoteMeta(token)
+			if prefix {
+				alternative += wordChars + "*"
+			}
+			alternatives = append(alternatives, alternative)
+		}
+	}
+	if len(alternatives) == 0 {
+		// Nothing but punctuation, look for it as it is.
+		return regexp.MustCompile("(?i)" + regexp.QuoteMeta(text))
+	}
+	return regexp.MustCompile(fmt.Sprintf("(?i)(?:^|[^%[1]s])(?P<hit>%[2]s)(?:[^%[1]s]|$)",
+		wordChars[1:len(wordChars)-1], strings.Join(alternatives, "|")))
+}
+
+// splitQuery splits a search on spaces, keeping double quoted values with
+// spaces together.
+func splitQuery(search string) []string {
+	var words []string
+	var current strings.Builder
+	quoted := false
+	for _, c := range search {
+		switch {
+		case c == '"':
+			quoted = !quoted
+			current.WriteRune(c)
+		case (c == ' ' || c == '\t') && !quoted:
+			if current.Len() > 0 {
+				words = append(words, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(c)
+		}
+	}
+	if current.Len() > 0 {
+		words = append(words, current.String())
+	}
+	return words
+}
+
+// isSearchField reports whether key can be used in a field filter: a field of
+// Post, a taxonomy of the site or the section.
+func (blog Blog) isSearchField(key string) bool {
+	key = strings.ToLower(key)
+	if key == "section" || postKeys[key] {
+		return true
+	}
+	_, ok := blog.taxonomy(key)
+	return ok
+}
+
+// taxonomy returns the plural name of a taxonomy given its singular or plural
+// name.
+func (blog Blog) taxonomy(name string) (string, bool) {
+	for singular, plural := range blog.Config.Taxonomies {
+		if name == singular || name == plural {
+			return plural, true
+		}
+	}
+	return "", false
+}
+
+func (blog Blog) matchesFields(post Post, filters []fieldFilter) bool {
+	for _, filter := range filters {
+		if !blog.matchesField(post, filter) {
+			return false
+		}
+	}
+	return true
+}
+
+func (blog Blog) matchesField(post Post, filter fieldFilter) bool {
+	if plural, ok := blog.taxonomy(filter.key); ok {
+		for _, term := range post.Terms(plural) {
+			if strings.EqualFold(term, filter.value) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var value interface{}
+	if filter.key == "section" {
+		value = post.Section
+	} else {
+		for key, v := range post.fields() {
+			if strings.ToLower(key) == filter.key {
+				value = v
+			}
+		}
+	}
+
+	switch v := value.(type) {
+	case bool:
+		want, err := strconv.ParseBool(filter.value)
+		return err == nil && v == want
+	case int:
+		want, err := strconv.Atoi(filter.value)
+		return err == nil && v == want
+	case []string:
+		for _, item := range v {
+			if strings.EqualFold(item, filter.value) {
+				return true
+			}
+		}
+		return false
+	}
+	return strings.Contains(strings.ToLower(toString(value)), strings.ToLower(filter.value))
+}
+
+// searchLines returns the lines of content that match re, with the position
+// of the first match on each line. If re has a group called "hit" only that
+// part of the match is highlighted.
+func searchLines(content []byte, re *regexp.Regexp) []Match {
+	var matches []Match
+	hit := re.SubexpIndex("hit")
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, len(content)+1)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		loc := re.FindStringSubmatchIndex(line)
+		if loc == nil {
+			continue
+		}
+		if hit > 0 && loc[2*hit] >= 0 {
+			loc = loc[2*hit:]
+		}
+		matches = append(matches, Match{Line: n, Text: line, Start: loc[0], End: loc[1]})
+	}
+	return matches
+}
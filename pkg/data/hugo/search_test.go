@@ -0,0 +1,53 @@
+package hugo
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/sudosays/hydra/pkg/data/index"
+)
+
+func TestSearch(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "content/blog/arrows.md", "---\ntitle: Arrows\ntags: [symbols]\n---\nleft ← right →\n")
+	writeFile(t, dir, "content/blog/go.md", "---\ntitle: Go\ntags: [go]\n---\nGoroutines and channels.\nMore goroutines.\n")
+	writeFile(t, dir, "content/notes/go.md", "---\ntitle: Note\ntags: [go]\n---\nA goroutine.\n")
+	blog := loadTestBlog(t, dir)
+	blog.index = index.New(filepath.Join(t.TempDir(), "index.gob"))
+	if err := blog.updateIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		search string
+		want   []string
+	}{
+		{"goroutines", []string{"blog/go.md"}},
+		{"gorout*", []string{"blog/go.md", "notes/go.md"}},
+		{"tag:go section:notes", []string{"notes/go.md"}},
+		{"→", []string{"blog/arrows.md"}},
+		{"/→/", []string{"blog/arrows.md"}},
+		{"right →", []string{"blog/arrows.md"}},
+		{"missing", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.search, func(t *testing.T) {
+			results, err := blog.Search(test.search)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, result := range results {
+				got = append(got, filepath.ToSlash(result.Post.Path))
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("found %q, want %q", got, test.want)
+			}
+			for i := range got {
+				if got[i] != "content/"+test.want[i] {
+					t.Errorf("found %q, want %q", got, test.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package hugo
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/sudosays/hydra/pkg/data/index"
+)
+
+// indexPath returns where the search index of a site is kept: in the user's
+// cache directory, named after a hash of the path of the site.
+func indexPath(sitePath string) (string, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(sitePath)
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum([]byte(abs))
+	return filepath.Join(cache, "hydra", "index-"+hex.EncodeToString(sum[:8])+".gob"), nil
+}
+
+// openIndex loads the search index of the site. A missing or unreadable cache
+// is not an error, the index is then kept in memory and built from scratch.
+func (blog *Blog) openIndex() {
+	blog.index = index.New("")
+	path, err := indexPath(blog.Path)
+	if err != nil {
+		Logger.Printf("The search index will not be saved: %s\n", err)
+		return
+	}
+	ix, err := index.Open(path)
+	if err != nil {
+		Logger.Printf("Rebuilding the search index: %s\n", err)
+		ix = index.New(path)
+	}
+	blog.index = ix
+}
+
+// updateIndex indexes the posts that changed since the index was saved, drops
+// the posts that no longer exist and saves the index.
+func (blog *Blog) updateIndex() error {
+	if blog.index == nil {
+		return nil
+	}
+	current := make(map[string]bool, len(blog.Posts))
+	for _, post := range blog.Posts {
+		current[post.Path] = true
+		if err := blog.indexPost(post.Path); err != nil {
+			return err
+		}
+	}
+	for _, id := range blog.index.IDs() {
+		if !current[id] {
+			blog.index.Remove(id)
+		}
+	}
+	blog.saveIndex()
+	return nil
+}
+
+// saveIndex writes the index to the cache. The index can always be rebuilt,
+// so failing to save it is only a warning.
+func (blog *Blog) saveIndex() {
+	if err := blog.SaveIndex(); err != nil {
+		Logger.Printf("Saving the search index failed: %s\n", err)
+	}
+}
+
+// SaveIndex writes the search index to the cache if it changed since it was
+// saved. Posts that are edited, added or removed only update the index in
+// memory, so that a batch of changes such as renaming a term on every post
+// writes it once: callers save it after every command and before quitting.
+func (blog *Blog) SaveIndex() error {
+	if blog.index == nil {
+		return nil
+	}
+	return blog.index.Save()
+}
+
+// indexPost indexes the file of a post again if it changed.
+func (blog *Blog) indexPost(relPath string) error {
+	if blog.index == nil {
+		return nil
+	}
+	filePath := filepath.Join(blog.Path, relPath)
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+	if blog.index.Current(relPath, info.ModTime(), info.Size()) {
+		return nil
+	}
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	blog.index.Update(relPath, content, info.ModTime())
+	return nil
+}
@@ -0,0 +1,67 @@
+package hugo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// archetypeDir is the directory, relative to the site, that holds the
+// templates Hugo creates new content from.
+const archetypeDir = "archetypes"
+
+// Sections lists the top level sections of the site: the directories in the
+// content directory, sorted by name.
+func (blog Blog) Sections() ([]string, error) {
+	entries, err := ioutil.ReadDir(blog.ContentDir())
+	if err != nil {
+		return nil, err
+	}
+	var sections []string
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			sections = append(sections, entry.Name())
+		}
+	}
+	return sections, nil
+}
+
+// Archetypes lists the kinds of content the site has archetypes for, which
+// can be given as PostOptions.Kind. Both archetype files (`archetypes/post.md`)
+// and directory based archetypes (`archetypes/gallery/index.md`) are listed.
+func (blog Blog) Archetypes() ([]string, error) {
+	entries, err := ioutil.ReadDir(filepath.Join(blog.Path, archetypeDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var kinds []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") || !entry.IsDir() && !isContentFile(name) {
+			continue
+		}
+		kind := strings.TrimSuffix(name, filepath.Ext(name))
+		if entry.IsDir() {
+			kind = name
+		}
+		if !contains(kinds, kind) {
+			kinds = append(kinds, kind)
+		}
+	}
+	sort.Strings(kinds)
+	return kinds, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+package hugo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultPort is the port Hugo serves a preview on if none is given.
+const defaultPort = 1313
+
+// serverLogLines is how many lines of the output of the server are kept.
+const serverLogLines = 500
+
+// stopTimeout is how long Stop waits for the server to exit after asking it to
+// before killing it.
+const stopTimeout = 5 * time.Second
+
+// serverURL finds the address in the line `hugo server` prints once it is
+// ready, e.g. "Web Server is available at http://localhost:1313/ (bind address
+// 127.0.0.1)".
+var serverURL = regexp.MustCompile(`Web Server is available at (\S+)`)
+
+// ServerOptions control how StartServer runs `hugo server`.
+type ServerOptions struct {
+	// Port is the port to serve on, 1313 if it is 0.
+	Port int
+	// Drafts and Future include drafts and posts dated in the future.
+	Drafts bool
+	Future bool
+	// OnOutput is called, from another goroutine, whenever the server prints
+	// a line or exits.
+	OnOutput func()
+}
+
+// A Server is a `hugo server` previewing a site, running in the background.
+type Server struct {
+	Options ServerOptions
+
+	cmd  *exec.Cmd
+	done chan struct{}
+
+	// lock guards the fields below, which are updated as the server runs.
+	lock sync.Mutex
+	url  string
+	log  []string
+	err  error
+}
+
+// StartServer runs `hugo server` for the site in the background. The server
+// keeps running until Stop is called, or it fails.
+func (blog Blog) StartServer(opts ServerOptions) (*Server, error) {
+	if opts.Port == 0 {
+		opts.Port = defaultPort
+	}
+	args := []string{"server", "--port", strconv.Itoa(opts.Port)}
+	if opts.Drafts {
+		args = append(args, "--buildDrafts")
+	}
+	if opts.Future {
+		args = append(args, "--buildFuture")
+	}
+
+	cmd := exec.Command(Binary, args...)
+	cmd.Dir = blog.Path
+	output, writer := io.Pipe()
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	if err := cmd.Start(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, ErrHugoNotFound
+		}
+		return nil, err
+	}
+
+	server := &Server{
+		Options: opts,
+		cmd:     cmd,
+		done:    make(chan struct{}),
+		url:     fmt.Sprintf("http://localhost:%d/", opts.Port),
+	}
+	read := make(chan struct{})
+	go func() {
+		server.readOutput(output)
+		close(read)
+	}()
+	go func() {
+		err := cmd.Wait()
+		writer.Close()
+		<-read
+		server.lock.Lock()
+		server.err = err
+		server.lock.Unlock()
+		close(server.done)
+		server.notify()
+	}()
+	return server, nil
+}
+
+// readOutput keeps the last lines the server printed, and the address it
+// serves on once it says so.
+func (server *Server) readOutput(output io.Reader) {
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		line := scanner.Text()
+		server.lock.Lock()
+		if match := serverURL.FindStringSubmatch(line); match != nil {
+			server.url = match[1]
+		}
+		server.log = append(server.log, line)
+		if len(server.log) > serverLogLines {
+			server.log = server.log[len(server.log)-serverLogLines:]
+		}
+		server.lock.Unlock()
+		server.notify()
+	}
+	// Keep draining the pipe so that the server never blocks on a write.
+	io.Copy(ioutil.Discard, output)
+}
+
+func (server *Server) notify() {
+	if server.Options.OnOutput != nil {
+		server.Options.OnOutput()
+	}
+}
+
+// URL returns the address the site is served on.
+func (server *Server) URL() string {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	return server.url
+}
+
+// Log returns the last n lines the server printed, or all of them if n is 0.
+func (server *Server) Log(n int) []string {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	lines := server.log
+	if n > 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return append([]string(nil), lines...)
+}
+
+// Running reports whether the server is still running.
+func (server *Server) Running() bool {
+	select {
+	case <-server.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Err returns why the server exited, or nil if it is running or was stopped
+// with Stop.
+func (server *Server) Err() error {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	return server.err
+}
+
+// Stop asks the server to exit and waits for it, killing it if it does not
+// exit in time.
+func (server *Server) Stop() {
+	if !server.Running() {
+		return
+	}
+	// Interrupting a process is not supported on Windows, so fall back to
+	// killing it straight away.
+	if err := server.cmd.Process.Signal(os.Interrupt); err != nil {
+		server.cmd.Process.Kill()
+	}
+	select {
+	case <-server.done:
+	case <-time.After(stopTimeout):
+		server.cmd.Process.Kill()
+		<-server.done
+	}
+	server.lock.Lock()
+	server.err = nil
+	server.lock.Unlock()
+}
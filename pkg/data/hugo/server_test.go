@@ -0,0 +1,108 @@
+package hugo
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeHugoServer stands in for `hugo server`: it reports the address it
+// serves on, like Hugo does once the site is built, and runs until it is
+// interrupted.
+const fakeHugoServer = `
+if [ "$1" != server ]; then echo "unexpected: $*" >&2; exit 2; fi
+echo "Built in 1 ms"
+echo "Web Server is available at http://127.0.0.1:$3/ (bind address 127.0.0.1)"
+trap 'echo "Shutting down"; exit 0' INT
+while :; do sleep 0.1; done
+`
+
+// waitFor fails the test if cond does not hold within a few seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStartServer(t *testing.T) {
+	calls := fakeHugo(t, fakeHugoServer)
+	dir := t.TempDir()
+	writeFile(t, dir, "content/_index.md", "")
+	blog := loadTestBlog(t, dir)
+
+	server, err := blog.StartServer(ServerOptions{Port: 1400, Drafts: true, Future: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	waitFor(t, "the address", func() bool { return server.URL() == "http://127.0.0.1:1400/" })
+	if !server.Running() {
+		t.Error("the server is not running")
+	}
+	if got := readFile(t, calls, ""); got != "server --port 1400 --buildDrafts --buildFuture\n" {
+		t.Errorf("hugo was run with %q", got)
+	}
+
+	server.Stop()
+	if server.Running() {
+		t.Error("the server is running after Stop")
+	}
+	if err := server.Err(); err != nil {
+		t.Errorf("a stopped server has error %s", err)
+	}
+	want := []string{"Web Server is available at http://127.0.0.1:1400/ (bind address 127.0.0.1)", "Shutting down"}
+	if got := server.Log(2); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("the log ends with %q, want %q", got, want)
+	}
+	if got := server.Log(0); len(got) != 3 {
+		t.Errorf("the whole log is %q, want 3 lines", got)
+	}
+}
+
+func TestServerFails(t *testing.T) {
+	fakeHugo(t, `echo "Error: serving on port $3 failed" >&2; exit 1`)
+	dir := t.TempDir()
+	writeFile(t, dir, "content/_index.md", "")
+	blog := loadTestBlog(t, dir)
+
+	notified := make(chan struct{}, 10)
+	server, err := blog.StartServer(ServerOptions{OnOutput: func() { notified <- struct{}{} }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the server to exit", func() bool { return !server.Running() })
+	if server.URL() != "http://localhost:1313/" {
+		t.Errorf("the address is %s, want the default port", server.URL())
+	}
+	if server.Err() == nil {
+		t.Error("a server that failed has no error")
+	}
+	if got := server.Log(0); len(got) != 1 || got[0] != "Error: serving on port 1313 failed" {
+		t.Errorf("the log is %q", got)
+	}
+	if len(notified) == 0 {
+		t.Error("OnOutput was not called")
+	}
+	// Stopping a server that exited does nothing, and keeps the error.
+	server.Stop()
+	if server.Err() == nil {
+		t.Error("Stop cleared the error of a server that failed")
+	}
+}
+
+func TestStartServerWithoutHugo(t *testing.T) {
+	binary := Binary
+	Binary = "hydra-test-no-such-hugo"
+	defer func() { Binary = binary }()
+
+	blog := Blog{Path: t.TempDir()}
+	if _, err := blog.StartServer(ServerOptions{}); !errors.Is(err, ErrHugoNotFound) {
+		t.Errorf("StartServer returned %v, want ErrHugoNotFound", err)
+	}
+}
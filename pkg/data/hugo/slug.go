@@ -0,0 +1,177 @@
+package hugo
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// defaultSlugLength is the longest slug Slugify makes if SlugOptions.MaxLength
+// is not set.
+const defaultSlugLength = 60
+
+// SlugOptions control how NewPost turns the title of a post into its file
+// name.
+type SlugOptions struct {
+	// MaxLength limits the number of characters in the slug, not counting a
+	// date prefix. Slugs are cut at a dash where possible. The default is 60.
+	MaxLength int
+	// DatePrefix starts the slug with the current date, e.g.
+	// `2026-10-18-my-title`.
+	DatePrefix bool
+	// Suffix adds a number to the slug (`my-title-2`) if a post with that name
+	// already exists, instead of refusing to create the post.
+	Suffix bool
+}
+
+// transliterations spell letters that have no ASCII decomposition in plain
+// ASCII. Accented Latin letters are handled by foldAccent.
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'ð': "d", 'þ': "th", 'ł': "l",
+	'đ': "d", 'ħ': "h", 'ı': "i", 'ĳ': "ij", 'ŋ': "ng", 'ſ': "s",
+
+	// Greek
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i",
+	'θ': "th", 'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x",
+	'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y",
+	'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o", 'ά': "a", 'έ': "e", 'ή': "i",
+	'ί': "i", 'ό': "o", 'ύ': "y", 'ώ': "o", 'ϊ': "i", 'ϋ': "y",
+
+	// Cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya", 'є': "ye",
+	'і': "i", 'ї': "yi", 'ґ': "g",
+}
+
+// accents maps the accented Latin letters to the letter without the accent,
+// grouped by base letter.
+var accents = map[rune]string{
+	'a': "àáâãäåāăą", 'c': "çćĉċč", 'd': "ď", 'e': "èéêëēĕėęě",
+	'g': "ĝğġģ", 'h': "ĥ", 'i': "ìíîïĩīĭįİ", 'j': "ĵ", 'k': "ķ",
+	'l': "ĺļľŀ", 'n': "ñńņňŉ", 'o': "òóôõöōŏő", 'r': "ŕŗř",
+	's': "śŝşšș", 't': "ţťț", 'u': "ùúûüũūŭůűų", 'w': "ŵ", 'y': "ýÿŷ",
+	'z': "źżž",
+}
+
+var foldAccent = make(map[rune]rune)
+
+func init() {
+	for base, letters := range accents {
+		for _, r := range letters {
+			foldAccent[r] = base
+		}
+	}
+}
+
+// Slugify turns a title into a string that is safe to use as a file name and
+// in a URL: lowercase letters and digits separated by single dashes, at most
+// maxLength characters long. Accented letters lose their accents and Greek and
+// Cyrillic are spelled in Latin letters. Letters of other scripts are kept,
+// while punctuation, symbols and emoji are dropped.
+func Slugify(title string, maxLength int) string {
+	if maxLength <= 0 {
+		maxLength = defaultSlugLength
+	}
+
+	var slug strings.Builder
+	dash := false
+	write := func(s string) {
+		if dash && slug.Len() > 0 {
+			slug.WriteByte('-')
+		}
+		dash = false
+		slug.WriteString(s)
+	}
+	for _, r := range strings.ToLower(title) {
+		if base, ok := foldAccent[r]; ok {
+			r = base
+		}
+		latin, ok := transliterations[r]
+		switch {
+		case r == '&':
+			dash = true
+			write("and")
+			dash = true
+		case ok:
+			write(latin)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			write(string(r))
+		case unicode.Is(unicode.Mn, r), r == '\'', r == '’':
+			// Combining accents (as in a decomposed "é") and apostrophes do
+			// not split words: "don't" becomes "dont".
+		default:
+			dash = true
+		}
+	}
+	return truncateSlug(slug.String(), maxLength)
+}
+
+// truncateSlug cuts a slug to at most max characters, at the last dash if
+// there is one in the second half.
+func truncateSlug(slug string, max int) string {
+	runes := []rune(slug)
+	if len(runes) <= max {
+		return slug
+	}
+	cut := runes[:max]
+	for i := len(cut) - 1; i > max/2; i-- {
+		if cut[i] == '-' {
+			cut = cut[:i]
+			break
+		}
+	}
+	return strings.Trim(string(cut), "-")
+}
+
+// postFilePath works out the path, relative to the content directory, of a new
+// post in a section. It refuses to reuse the name of an existing post, a file
+// or a bundle, unless opts.Suffix is set in which case a number is added.
+func (blog Blog) postFilePath(section, title, extension string, bundle bool, opts SlugOptions) (string, error) {
+	slug := Slugify(title, opts.MaxLength)
+	if slug == "" {
+		return "", fmt.Errorf("cannot make a file name from the title '%s'", title)
+	}
+	if opts.DatePrefix {
+		slug = time.Now().Format("2006-01-02") + "-" + slug
+	}
+
+	name := slug
+	for n := 2; blog.postExists(section, name, extension); n++ {
+		if !opts.Suffix {
+			return "", fmt.Errorf("%w: %s", ErrPostExists, path.Join(blog.contentDir(), section, name))
+		}
+		name = fmt.Sprintf("%s-%d", slug, n)
+	}
+
+	if bundle {
+		return fmt.Sprintf("%s/%s/index.%s", section, name, extension), nil
+	}
+	return fmt.Sprintf("%s/%s.%s", section, name, extension), nil
+}
+
+// postExists reports whether a section already has a post called name, either
+// a content file with any extension or a bundle directory. Both would end up
+// at the same URL.
+func (blog Blog) postExists(section, name, extension string) bool {
+	dir := filepath.Join(blog.ContentDir(), section)
+	if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+		return true
+	}
+	if _, err := os.Stat(filepath.Join(dir, name+"."+extension)); err == nil {
+		return true
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, name+".*"))
+	for _, match := range matches {
+		if isContentFile(match) {
+			return true
+		}
+	}
+	return false
+}
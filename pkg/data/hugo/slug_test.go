@@ -0,0 +1,53 @@
+package hugo
+
+import "testing"
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		title string
+		max   int
+		want  string
+	}{
+		{"Hello, World!", 0, "hello-world"},
+		{"  Don't   panic -- it's fine  ", 0, "dont-panic-its-fine"},
+		{"Tom & Jerry", 0, "tom-and-jerry"},
+		{"Crème brûlée à la café", 0, "creme-brulee-a-la-cafe"},
+		{"Crème", 0, "creme"},
+		{"Straße Ærø", 0, "strasse-aero"},
+		{"Ελληνικά και Русский", 0, "ellinika-kai-russkiy"},
+		{"你好，世界", 0, "你好-世界"},
+		{"Emoji 🎉 party 🚀!", 0, "emoji-party"},
+		{"🎉🚀", 0, ""},
+		{"C++ & Go: 2021 (part 1/2)", 0, "c-and-go-2021-part-1-2"},
+		// Long slugs are cut at the last dash in the second half.
+		{"one two three four five", 12, "one-two"},
+		{"one two three four five", 14, "one-two-three"},
+		{"abcdefghijklmnop qr", 10, "abcdefghij"},
+		{"hello 世界世界世界 and more", 12, "hello-世界世界世界"},
+		{"世界世界世界世界 世界", 10, "世界世界世界世界"},
+		{"hello-世界-hello-世界-hello", 11, "hello-世界"},
+		// The halves are counted in characters, not bytes.
+		{"世界世界 abcdefgh", 10, "世界世界-abcde"},
+		{"abcdefgh 世界世界世界", 12, "abcdefgh"},
+		{"a b c", 0, "a-b-c"},
+	}
+	for _, test := range tests {
+		if got := Slugify(test.title, test.max); got != test.want {
+			t.Errorf("Slugify(%q, %d) = %q, want %q", test.title, test.max, got, test.want)
+		}
+	}
+}
+
+func TestSlugifyDefaultLength(t *testing.T) {
+	title := ""
+	for i := 0; i < 20; i++ {
+		title += "wörd "
+	}
+	slug := Slugify(title, 0)
+	if n := len([]rune(slug)); n > defaultSlugLength || n < defaultSlugLength-5 {
+		t.Errorf("the slug is %d characters long: %q", n, slug)
+	}
+	if slug[len(slug)-4:] != "word" {
+		t.Errorf("the slug was not cut between words: %q", slug)
+	}
+}
@@ -0,0 +1,192 @@
+// Package taxonomy manages the taxonomy terms (tags, categories and any other
+// taxonomies a site configures) used by the posts of a hugo.Blog.
+package taxonomy
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sudosays/hydra/pkg/data/hugo"
+)
+
+// A Term is a taxonomy term and the posts that use it. Posts holds indices
+// into Blog.Posts.
+type Term struct {
+	Name  string
+	Posts []int
+}
+
+// Count returns the number of posts that use the term.
+func (t Term) Count() int {
+	return len(t.Posts)
+}
+
+// An Index maps the plural name of every taxonomy of a site to its terms,
+// sorted by name. Terms are matched case-insensitively, like Hugo does.
+type Index map[string][]Term
+
+// Build indexes the terms used by all of the posts of a blog, for each of the
+// taxonomies the site is configured with.
+func Build(blog hugo.Blog) Index {
+	index := make(Index)
+	for _, taxonomy := range Taxonomies(blog) {
+		byKey := make(map[string]*Term)
+		var keys []string
+		for i, post := range blog.Posts {
+			for _, name := range post.Terms(taxonomy) {
+				key := termKey(name)
+				term, ok := byKey[key]
+				if !ok {
+					term = &Term{Name: name}
+					byKey[key] = term
+					keys = append(keys, key)
+				}
+				if len(term.Posts) == 0 || term.Posts[len(term.Posts)-1] != i {
+					term.Posts = append(term.Posts, i)
+				}
+			}
+		}
+
+		sort.Strings(keys)
+		terms := make([]Term, 0, len(keys))
+		for _, key := range keys {
+			terms = append(terms, *byKey[key])
+		}
+		index[taxonomy] = terms
+	}
+	return index
+}
+
+// Find looks up a term of a taxonomy.
+func (index Index) Find(taxonomy, name string) (Term, bool) {
+	for _, term := range index[taxonomy] {
+		if termKey(term.Name) == termKey(name) {
+			return term, true
+		}
+	}
+	return Term{}, false
+}
+
+// Taxonomies returns the plural names of the taxonomies of a blog, sorted.
+func Taxonomies(blog hugo.Blog) []string {
+	var names []string
+	for _, plural := range blog.Config.Taxonomies {
+		names = append(names, plural)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Check explains why a blog has no taxonomies, and so no terms, naming the
+// configuration files of the site they would be set in. It returns nil if the
+// blog has any taxonomies.
+func Check(blog hugo.Blog) error {
+	if len(blog.Config.Taxonomies) > 0 {
+		return nil
+	}
+	if len(blog.Config.Files) == 0 {
+		return errors.New("the site has no taxonomies and no configuration file to set them in")
+	}
+	return fmt.Errorf("the site has no taxonomies: none are set in %s", strings.Join(blog.Config.Files, ", "))
+}
+
+// Resolve finds the plural name of a taxonomy of the blog from either its
+// singular or plural name.
+func Resolve(blog hugo.Blog, name string) (string, error) {
+	if err := Check(blog); err != nil {
+		return "", err
+	}
+	name = strings.ToLower(name)
+	for singular, plural := range blog.Config.Taxonomies {
+		if name == singular || name == plural {
+			return plural, nil
+		}
+	}
+	return "", fmt.Errorf("the site has no taxonomy called '%s', only %s", name, strings.Join(Taxonomies(blog), ", "))
+}
+
+// Rename changes a term to a new name on every post that uses it. It refuses
+// to rename a term to one that already exists; use Merge for that. It returns
+// the number of posts that were changed.
+func Rename(blog *hugo.Blog, taxonomy, from, to string) (int, error) {
+	index := Build(*blog)
+	if _, ok := index.Find(taxonomy, from); !ok {
+		return 0, fmt.Errorf("no posts use the %s term '%s'", taxonomy, from)
+	}
+	if existing, ok := index.Find(taxonomy, to); ok && termKey(from) != termKey(to) {
+		return 0, fmt.Errorf("the %s term '%s' already exists, merge the terms instead", taxonomy, existing.Name)
+	}
+	return rewrite(blog, taxonomy, from, to)
+}
+
+// Merge moves every post from one term to another existing term, removing the
+// first term. It returns the number of posts that were changed.
+func Merge(blog *hugo.Blog, taxonomy, from, into string) (int, error) {
+	index := Build(*blog)
+	if _, ok := index.Find(taxonomy, from); !ok {
+		return 0, fmt.Errorf("no posts use the %s term '%s'", taxonomy, from)
+	}
+	target, ok := index.Find(taxonomy, into)
+	if !ok {
+		return 0, fmt.Errorf("no posts use the %s term '%s', rename the term instead", taxonomy, into)
+	}
+	return rewrite(blog, taxonomy, from, target.Name)
+}
+
+// Delete removes a term from every post that uses it. It returns the number of
+// posts that were changed.
+func Delete(blog *hugo.Blog, taxonomy, name string) (int, error) {
+	if _, ok := Build(*blog).Find(taxonomy, name); !ok {
+		return 0, fmt.Errorf("no posts use the %s term '%s'", taxonomy, name)
+	}
+	return rewrite(blog, taxonomy, name, "")
+}
+
+// rewrite replaces the term from with to (or removes it if to is empty) on
+// every post and saves the posts that changed.
+func rewrite(blog *hugo.Blog, taxonomy, from, to string) (int, error) {
+	// Saving a post can reorder Blog.Posts, so work from a copy.
+	posts := append([]hugo.Post{}, blog.Posts...)
+	changed := 0
+	for _, post := range posts {
+		terms, ok := replaceTerm(post.Terms(taxonomy), from, to)
+		if !ok {
+			continue
+		}
+		post.SetTerms(taxonomy, terms)
+		if _, err := blog.UpdatePost(post); err != nil {
+			return changed, fmt.Errorf("%s: %w", post.Path, err)
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// replaceTerm replaces from with to in a list of terms, dropping duplicates
+// that the replacement creates. It reports whether the list changed.
+func replaceTerm(terms []string, from, to string) ([]string, bool) {
+	replaced := false
+	seen := make(map[string]bool)
+	var result []string
+	for _, term := range terms {
+		if termKey(term) == termKey(from) {
+			replaced = true
+			if to == "" {
+				continue
+			}
+			term = to
+		}
+		if seen[termKey(term)] {
+			continue
+		}
+		seen[termKey(term)] = true
+		result = append(result, term)
+	}
+	return result, replaced
+}
+
+func termKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
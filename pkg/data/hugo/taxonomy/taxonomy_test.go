@@ -0,0 +1,51 @@
+package taxonomy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sudosays/hydra/pkg/data/hugo"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		config hugo.SiteConfig
+		want   string
+	}{
+		{"taxonomies", hugo.SiteConfig{Taxonomies: map[string]string{"tag": "tags"}}, ""},
+		{"none configured", hugo.SiteConfig{Files: []string{"hugo.toml", "config/_default/taxonomies.yaml"}},
+			"none are set in hugo.toml, config/_default/taxonomies.yaml"},
+		{"no config", hugo.SiteConfig{}, "no configuration file"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Check(hugo.Blog{Config: test.config})
+			if test.want == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got %v, want an error containing %q", err, test.want)
+			}
+			if _, resolveErr := Resolve(hugo.Blog{Config: test.config}, "tags"); resolveErr == nil || resolveErr.Error() != err.Error() {
+				t.Errorf("Resolve returned %v, want %v", resolveErr, err)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	blog := hugo.Blog{Config: hugo.SiteConfig{Taxonomies: map[string]string{"tag": "tags", "series": "series"}}}
+	for name, want := range map[string]string{"tag": "tags", "Tags": "tags", "series": "series"} {
+		if got, err := Resolve(blog, name); err != nil || got != want {
+			t.Errorf("Resolve(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+	_, err := Resolve(blog, "category")
+	if err == nil || !strings.Contains(err.Error(), "only series, tags") {
+		t.Errorf("Resolve(category) returned %v", err)
+	}
+}
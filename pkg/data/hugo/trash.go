@@ -0,0 +1,165 @@
+package hugo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// trashDir is where deleted posts are kept, relative to the site. Every
+// deleted post gets a directory of its own, holding the post (or its whole
+// page bundle) and a metadata file describing where it came from.
+const trashDir = ".hydra/trash"
+
+const trashMetadata = "trash.json"
+
+// A TrashItem is a post that was deleted and can be restored.
+type TrashItem struct {
+	ID      string    `json:"-"`
+	Path    string    `json:"path"`
+	Title   string    `json:"title"`
+	Bundle  bool      `json:"bundle"`
+	Deleted time.Time `json:"deleted"`
+}
+
+// movedPath returns the path, relative to the site, of the file or directory
+// that is moved when the item is deleted or restored. For a page bundle that
+// is the whole bundle directory.
+func (item TrashItem) movedPath() string {
+	return postRoot(item.Path)
+}
+
+// DeletePost moves the post from the site's content/section directory to the
+// trash, from where it can be restored with RestorePost. Posts that are page
+// bundles are moved together with all of their resources.
+func (blog *Blog) DeletePost(deletePath string) error {
+	item := TrashItem{
+		Path:    deletePath,
+		Title:   deletePath,
+		Bundle:  isBundleIndex(path.Base(deletePath)),
+		Deleted: time.Now(),
+	}
+	if post, err := blog.FindPost(deletePath); err == nil && post.Title != "" {
+		item.Title = post.Title
+	}
+
+	source := filepath.Join(blog.Path, item.movedPath())
+	if _, err := os.Stat(source); os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s", ErrPostNotFound, deletePath)
+	}
+
+	itemDir, err := blog.newTrashDir(item.Deleted)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(item, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(itemDir, trashMetadata), data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(source, filepath.Join(itemDir, filepath.Base(source))); err != nil {
+		os.RemoveAll(itemDir)
+		return err
+	}
+	return blog.forgetPost(deletePath)
+}
+
+// newTrashDir creates an empty directory in the trash for one deleted post.
+// The trash is kept out of git with a .gitignore file.
+func (blog Blog) newTrashDir(deleted time.Time) (string, error) {
+	trash := filepath.Join(blog.Path, trashDir)
+	if err := os.MkdirAll(trash, 0755); err != nil {
+		return "", err
+	}
+	ignore := filepath.Join(blog.Path, path.Dir(trashDir), ".gitignore")
+	if _, err := os.Stat(ignore); os.IsNotExist(err) {
+		if err := ioutil.WriteFile(ignore, []byte("*\n"), 0644); err != nil {
+			return "", err
+		}
+	}
+
+	id := strconv.FormatInt(deleted.UnixNano(), 10)
+	dir := filepath.Join(trash, id)
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// Trash lists the deleted posts of the site, most recently deleted first.
+func (blog Blog) Trash() ([]TrashItem, error) {
+	entries, err := ioutil.ReadDir(filepath.Join(blog.Path, trashDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var items []TrashItem
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(blog.Path, trashDir, entry.Name(), trashMetadata))
+		if err != nil {
+			return nil, err
+		}
+		item := TrashItem{ID: entry.Name()}
+		if err := json.Unmarshal(data, &item); err != nil {
+			return nil, fmt.Errorf("the trash metadata of %s is invalid: %w", entry.Name(), err)
+		}
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Deleted.After(items[j].Deleted)
+	})
+	return items, nil
+}
+
+// RestorePost moves a deleted post from the trash back to where it was. It
+// refuses to overwrite a post that has been created in its place since.
+func (blog *Blog) RestorePost(item TrashItem) error {
+	itemDir := filepath.Join(blog.Path, trashDir, item.ID)
+	target := filepath.Join(blog.Path, item.movedPath())
+	if _, err := os.Stat(target); err == nil {
+		return fmt.Errorf("cannot restore %s, the file already exists", item.movedPath())
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(filepath.Join(itemDir, filepath.Base(target)), target); err != nil {
+		if os.IsNotExist(err) {
+			return errors.New("the trash does not contain " + item.movedPath())
+		}
+		return err
+	}
+	if err := os.RemoveAll(itemDir); err != nil {
+		return err
+	}
+	return blog.refreshPost(item.Path)
+}
+
+// EmptyTrash permanently removes every post in the trash. It returns the
+// number of posts that were removed.
+func (blog Blog) EmptyTrash() (int, error) {
+	items, err := blog.Trash()
+	if err != nil {
+		return 0, err
+	}
+	for i, item := range items {
+		if err := os.RemoveAll(filepath.Join(blog.Path, trashDir, item.ID)); err != nil {
+			return i, err
+		}
+	}
+	return len(items), nil
+}
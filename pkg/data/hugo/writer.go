@@ -0,0 +1,698 @@
+package hugo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The front matter writer edits the raw text of a front matter block one key
+// at a time. Lines that are not touched by a change, including comments, key
+// order and keys hydra does not know about, are kept exactly as they were.
+
+// setFrontMatter sets key to value in the front matter block. Keys are matched
+// case-insensitively, and the original spelling of an existing key is kept. A
+// nil value removes the key.
+func setFrontMatter(format Format, fm []byte, key string, value interface{}) ([]byte, error) {
+	switch format {
+	case YAML:
+		return editLines(fm, yamlEditor{}, key, value)
+	case TOML:
+		return editLines(fm, tomlEditor{}, key, value)
+	case Org:
+		return editLines(fm, orgEditor{}, key, value)
+	case JSON:
+		return setJSON(fm, key, value)
+	}
+	return nil, fmt.Errorf("unsupported front matter format: %s", format)
+}
+
+// lineEditor knows how to find and encode keys of a line based format.
+type lineEditor interface {
+	// bounds returns the range of lines that may hold keys.
+	bounds(lines []string) (int, int)
+	// find returns the lines [start, end) holding a top level key, or -1.
+	find(lines []string, first, last int, key string) (int, int, string)
+	// insertAt returns the line where a new key should be added.
+	insertAt(lines []string, first, last int) int
+	// encode returns the lines for key set to value. old holds the lines
+	// previously used for the key, if any, so their style can be kept.
+	encode(key string, value interface{}, old []string) ([]string, error)
+}
+
+func editLines(fm []byte, editor lineEditor, key string, value interface{}) ([]byte, error) {
+	lines := strings.SplitAfter(string(fm), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	first, last := editor.bounds(lines)
+	start, end, name := editor.find(lines, first, last, key)
+
+	var replacement, old []string
+	if start >= 0 {
+		old = lines[start:end]
+		key = name
+	} else {
+		start = editor.insertAt(lines, first, last)
+		end = start
+	}
+	if value != nil {
+		encoded, err := editor.encode(key, value, old)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range encoded {
+			replacement = append(replacement, line+"\n")
+		}
+	}
+
+	edited := append([]string{}, lines[:start]...)
+	edited = append(edited, replacement...)
+	edited = append(edited, lines[end:]...)
+	return []byte(strings.Join(edited, "")), nil
+}
+
+// delimitedBounds returns the lines between the opening and closing delimiter.
+func delimitedBounds(lines []string) (int, int) {
+	last := len(lines) - 1
+	if last < 1 {
+		return 1, 1
+	}
+	return 1, last
+}
+
+func lineIndent(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// trailingComment returns the comment at the end of a line, if any.
+func trailingComment(line string) string {
+	line = strings.TrimRight(line, "\r\n")
+	return strings.TrimSpace(line[len(stripComment(line)):])
+}
+
+// YAML
+
+type yamlEditor struct{}
+
+func (yamlEditor) bounds(lines []string) (int, int) {
+	return delimitedBounds(lines)
+}
+
+func (yamlEditor) find(lines []string, first, last int, key string) (int, int, string) {
+	for i := first; i < last; i++ {
+		line := strings.TrimRight(lines[i], "\r\n")
+		if lineIndent(line) != "" {
+			continue
+		}
+		name, _, ok := splitYAMLKey(line)
+		if !ok || !strings.EqualFold(name, key) {
+			continue
+		}
+		// The value runs on over indented lines and list items. Blank lines
+		// belong to it only when more of it follows, as in a block scalar
+		// with paragraphs.
+		end := i + 1
+		for next := end; next < last; next++ {
+			line := strings.TrimRight(lines[next], "\r\n")
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if lineIndent(line) == "" && !isYAMLListItem(line) {
+				break
+			}
+			end = next + 1
+		}
+		return i, end, name
+	}
+	return -1, -1, ""
+}
+
+func (yamlEditor) insertAt(lines []string, first, last int) int {
+	return last
+}
+
+func (yamlEditor) encode(key string, value interface{}, old []string) ([]string, error) {
+	// Keep the style of an existing list: flow (`[a, b]`) or block with the
+	// same indentation for its items.
+	// Quoted strings stay quoted.
+	flow := false
+	listIndent := "  "
+	comment := ""
+	quote := byte(0)
+	if len(old) > 0 {
+		_, rest, _ := splitYAMLKey(strings.TrimRight(old[0], "\r\n"))
+		flow = rest != ""
+		if len(old) > 1 {
+			listIndent = lineIndent(old[1])
+		}
+		if len(old) == 1 {
+			comment = trailingComment(old[0])
+		}
+		if rest != "" && (rest[0] == '"' || rest[0] == '\'') {
+			quote = rest[0]
+		}
+	}
+
+	if s, ok := value.(string); ok && quote != 0 {
+		line := yamlKey(key) + ": " + strconv.Quote(s)
+		if quote == '\'' {
+			line = yamlKey(key) + ": '" + strings.ReplaceAll(s, "'", "''") + "'"
+		}
+		if comment != "" {
+			line += " " + comment
+		}
+		return []string{line}, nil
+	}
+
+	lines, err := encodeYAML(yamlKey(key), value, "", flow, listIndent)
+	if err != nil {
+		return nil, err
+	}
+	if comment != "" && len(lines) == 1 {
+		lines[0] += " " + comment
+	}
+	return lines, nil
+}
+
+func encodeYAML(key string, value interface{}, indent string, flow bool, listIndent string) ([]string, error) {
+	value = normaliseValue(value)
+	switch v := value.(type) {
+	case []interface{}:
+		if flow || len(v) == 0 {
+			items := make([]string, len(v))
+			for i, item := range v {
+				s, err := yamlScalar(item)
+				if err != nil {
+					return nil, err
+				}
+				items[i] = s
+			}
+			return []string{indent + key + ": [" + strings.Join(items, ", ") + "]"}, nil
+		}
+		lines := []string{indent + key + ":"}
+		for _, item := range v {
+			if m, ok := item.(map[string]interface{}); ok {
+				itemLines, err := encodeYAMLMap(m, indent+listIndent+"  ")
+				if err != nil {
+					return nil, err
+				}
+				if len(itemLines) > 0 {
+					itemLines[0] = indent + listIndent + "- " + strings.TrimLeft(itemLines[0], " ")
+				}
+				lines = append(lines, itemLines...)
+				continue
+			}
+			s, err := yamlScalar(item)
+			if err != nil {
+				return nil, err
+			}
+			lines = append(lines, indent+listIndent+"- "+s)
+		}
+		return lines, nil
+	case map[string]interface{}:
+		nested, err := encodeYAMLMap(v, indent+"  ")
+		if err != nil {
+			return nil, err
+		}
+		if len(nested) == 0 {
+			return []string{indent + key + ": {}"}, nil
+		}
+		return append([]string{indent + key + ":"}, nested...), nil
+	}
+	s, err := yamlScalar(value)
+	if err != nil {
+		return nil, err
+	}
+	return []string{indent + key + ": " + s}, nil
+}
+
+func encodeYAMLMap(m map[string]interface{}, indent string) ([]string, error) {
+	var lines []string
+	for _, key := range sortedKeys(m) {
+		entry, err := encodeYAML(yamlKey(key), m[key], indent, false, "  ")
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, entry...)
+	}
+	return lines, nil
+}
+
+func yamlKey(key string) string {
+	if yamlPlainSafe(key) {
+		return key
+	}
+	return strconv.Quote(key)
+}
+
+func yamlScalar(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "null", nil
+	case string:
+		if yamlPlainSafe(v) {
+			return v, nil
+		}
+		return strconv.Quote(v), nil
+	case bool, int, float64:
+		return fmt.Sprint(v), nil
+	}
+	return "", fmt.Errorf("cannot write %T as a yaml value", value)
+}
+
+// yamlPlainSafe reports whether s can be written without quotes and read back
+// as the same string.
+func yamlPlainSafe(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s || strings.ContainsAny(s, "\n\r\t") {
+		return false
+	}
+	if strings.ContainsAny(s[:1], "-?:!|>'\"%@`&*") || strings.ContainsAny(s, ",[]{}#") {
+		return false
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return false
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(s), &node); err != nil || len(node.Content) == 0 {
+		return false
+	}
+	scalar := node.Content[0]
+	tag := scalar.ShortTag()
+	return scalar.Kind == yaml.ScalarNode && (tag == "!!str" || tag == "!!timestamp") && scalar.Value == s
+}
+
+// TOML
+
+type tomlEditor struct{}
+
+func (tomlEditor) bounds(lines []string) (int, int) {
+	return delimitedBounds(lines)
+}
+
+var tomlHeader = regexp.MustCompile(`^\s*\[\[?\s*([^\]]+?)\s*\]\]?\s*(#.*)?$`)
+
+func (tomlEditor) find(lines []string, first, last int, key string) (int, int, string) {
+	inTables := false
+	for i := first; i < last; i++ {
+		line := strings.TrimRight(lines[i], "\r\n")
+		if m := tomlHeader.FindStringSubmatch(line); m != nil && isTableHeader(line) {
+			inTables = true
+			// A table for the key replaces everything up to the next table
+			// that is not one of its children.
+			name := unquoteKey(m[1])
+			if !strings.EqualFold(name, key) {
+				continue
+			}
+			end := i + 1
+			for end < last {
+				h := tomlHeader.FindStringSubmatch(strings.TrimRight(lines[end], "\r\n"))
+				if h != nil && !strings.HasPrefix(strings.ToLower(h[1]), strings.ToLower(name)+".") {
+					break
+				}
+				end++
+			}
+			return i, end, name
+		}
+		if inTables {
+			continue
+		}
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			continue
+		}
+		name := unquoteKey(strings.TrimSpace(line[:eq]))
+		if !strings.EqualFold(name, key) {
+			continue
+		}
+		raw := strings.TrimSpace(stripComment(line[eq+1:]))
+		end := i + 1
+		switch {
+		case strings.HasPrefix(raw, "\"\"\"") || strings.HasPrefix(raw, "'''"):
+			quote := raw[:3]
+			for strings.Count(raw, quote) < 2 && end < last {
+				raw += lines[end]
+				end++
+			}
+		case strings.HasPrefix(raw, "["):
+			for !balanced(raw) && end < last {
+				raw += stripComment(lines[end])
+				end++
+			}
+		}
+		return i, end, strings.TrimSpace(line[:eq])
+	}
+	return -1, -1, ""
+}
+
+func isTableHeader(line string) bool {
+	return tomlHeader.MatchString(line) && !strings.Contains(stripComment(line), "=")
+}
+
+func (tomlEditor) insertAt(lines []string, first, last int) int {
+	// New keys go after the last top level key, before any tables.
+	at := first
+	for i := first; i < last; i++ {
+		line := strings.TrimRight(lines[i], "\r\n")
+		if isTableHeader(line) {
+			break
+		}
+		if strings.TrimSpace(line) != "" && !strings.HasPrefix(strings.TrimSpace(line), "#") {
+			at = i + 1
+		}
+	}
+	return at
+}
+
+func (tomlEditor) encode(key string, value interface{}, old []string) ([]string, error) {
+	value = normaliseValue(value)
+	if m, ok := value.(map[string]interface{}); ok && len(old) > 0 && isTableHeader(strings.TrimRight(old[0], "\r\n")) {
+		indent := ""
+		if len(old) > 1 {
+			indent = lineIndent(old[1])
+		}
+		return encodeTOMLTable(tomlKey(key), m, indent)
+	}
+
+	// Dates are written as TOML datetimes unless the site quoted them.
+	bareDate := true
+	comment := ""
+	if len(old) > 0 {
+		line := strings.TrimRight(old[0], "\r\n")
+		raw := strings.TrimSpace(stripComment(line[strings.Index(line, "=")+1:]))
+		bareDate = raw != "" && raw[0] != '"' && raw[0] != '\''
+		if len(old) == 1 {
+			comment = trailingComment(line)
+		}
+	}
+	s, err := tomlValue(value, bareDate)
+	if err != nil {
+		return nil, err
+	}
+	line := tomlKey(key) + " = " + s
+	if len(old) > 0 && strings.Contains(old[0], "=") {
+		line = strings.TrimRight(old[0][:strings.Index(old[0], "=")], " ") + " = " + s
+	}
+	if comment != "" {
+		line += " " + comment
+	}
+	return []string{line}, nil
+}
+
+func encodeTOMLTable(name string, m map[string]interface{}, indent string) ([]string, error) {
+	lines := []string{"[" + name + "]"}
+	var tables []string
+	for _, key := range sortedKeys(m) {
+		if nested, ok := m[key].(map[string]interface{}); ok {
+			sub, err := encodeTOMLTable(name+"."+tomlKey(key), nested, indent)
+			if err != nil {
+				return nil, err
+			}
+			tables = append(tables, sub...)
+			continue
+		}
+		s, err := tomlValue(m[key], true)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, indent+tomlKey(key)+" = "+s)
+	}
+	return append(lines, tables...), nil
+}
+
+var tomlBareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func tomlKey(key string) string {
+	if tomlBareKey.MatchString(key) {
+		return key
+	}
+	return strconv.Quote(key)
+}
+
+func tomlValue(value interface{}, bareDate bool) (string, error) {
+	switch v := normaliseValue(value).(type) {
+	case string:
+		if bareDate && isDateString(v) {
+			return v, nil
+		}
+		return strconv.Quote(v), nil
+	case bool, int, float64:
+		return fmt.Sprint(v), nil
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, err := tomlValue(item, false)
+			if err != nil {
+				return "", err
+			}
+			items[i] = s
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case map[string]interface{}:
+		var items []string
+		for _, key := range sortedKeys(v) {
+			s, err := tomlValue(v[key], true)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, tomlKey(key)+" = "+s)
+		}
+		return "{ " + strings.Join(items, ", ") + " }", nil
+	}
+	return "", fmt.Errorf("cannot write %T as a toml value", value)
+}
+
+// isDateString reports whether s is a full RFC3339 date, or a plain date.
+func isDateString(s string) bool {
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if _, err := time.Parse(layout, s); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Org mode
+
+type orgEditor struct{}
+
+func (orgEditor) bounds(lines []string) (int, int) {
+	return 0, len(lines)
+}
+
+func (orgEditor) find(lines []string, first, last int, key string) (int, int, string) {
+	for i := first; i < last; i++ {
+		line := strings.TrimSpace(lines[i])
+		colon := strings.Index(line, ":")
+		if !strings.HasPrefix(line, "#+") || colon < 0 {
+			continue
+		}
+		name := line[2:colon]
+		if strings.EqualFold(strings.TrimSuffix(name, "[]"), key) {
+			return i, i + 1, name
+		}
+	}
+	return -1, -1, ""
+}
+
+func (orgEditor) insertAt(lines []string, first, last int) int {
+	return last
+}
+
+func (orgEditor) encode(key string, value interface{}, old []string) ([]string, error) {
+	name := strings.TrimSuffix(key, "[]")
+	if len(old) == 0 {
+		name = strings.ToUpper(name)
+	}
+
+	var s string
+	switch v := normaliseValue(value).(type) {
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = toString(item)
+		}
+		s = strings.Join(items, " ")
+		if len(old) == 0 || strings.HasSuffix(key, "[]") {
+			name += "[]"
+		}
+	case map[string]interface{}:
+		return nil, fmt.Errorf("cannot write a map to org front matter: %s", key)
+	case string:
+		s = v
+		// Keep org timestamps as timestamps.
+		if len(old) > 0 && isOrgTimestamp(old[0]) {
+			if t := parseDate(v); !t.IsZero() {
+				s = formatOrgTimestamp(t)
+			}
+		}
+	default:
+		s = toString(v)
+	}
+	return []string{"#+" + name + ": " + s}, nil
+}
+
+func isOrgTimestamp(line string) bool {
+	colon := strings.Index(line, ":")
+	value := strings.TrimSpace(line[colon+1:])
+	return strings.HasPrefix(value, "<") || strings.HasPrefix(value, "[")
+}
+
+func formatOrgTimestamp(t time.Time) string {
+	if t.Hour() == 0 && t.Minute() == 0 {
+		return t.Format("<2006-01-02 Mon>")
+	}
+	return t.Format("<2006-01-02 Mon 15:04>")
+}
+
+// JSON
+
+type jsonKeySpan struct {
+	name                 string
+	start, keyStart      int
+	valueStart, valueEnd int
+}
+
+// jsonKeySpans finds the byte ranges of the top level keys in a JSON object.
+// start is the comma before the key (or the key itself for the first one) and
+// keyStart is the opening quote of the key.
+func jsonKeySpans(fm []byte) ([]jsonKeySpan, error) {
+	decoder := json.NewDecoder(bytes.NewReader(fm))
+	if tok, err := decoder.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("json front matter must be an object")
+	}
+
+	var spans []jsonKeySpan
+	for decoder.More() {
+		start := int(decoder.InputOffset())
+		tok, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		name, _ := tok.(string)
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, err
+		}
+		end := int(decoder.InputOffset())
+		keyStart := start + bytes.IndexByte(fm[start:], '"')
+		spans = append(spans, jsonKeySpan{
+			name:       name,
+			start:      start,
+			keyStart:   keyStart,
+			valueStart: end - len(raw),
+			valueEnd:   end,
+		})
+	}
+	return spans, nil
+}
+
+func setJSON(fm []byte, key string, value interface{}) ([]byte, error) {
+	spans, err := jsonKeySpans(fm)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, span := range spans {
+		if !strings.EqualFold(span.name, key) {
+			continue
+		}
+		if value == nil {
+			from, to := span.start, span.valueEnd
+			if i == 0 && len(spans) > 1 {
+				// The first key has no comma before it, so remove everything
+				// up to the next key instead.
+				to = spans[1].keyStart
+			}
+			return splice(fm, from, to, ""), nil
+		}
+		indent := jsonIndent(fm, span.keyStart)
+		encoded, err := marshalJSON(value, indent)
+		if err != nil {
+			return nil, err
+		}
+		return splice(fm, span.valueStart, span.valueEnd, encoded), nil
+	}
+
+	if value == nil {
+		return fm, nil
+	}
+
+	indent := "  "
+	at := bytes.LastIndexByte(fm, '}')
+	prefix := "\n"
+	if len(spans) > 0 {
+		last := spans[len(spans)-1]
+		indent = jsonIndent(fm, last.keyStart)
+		at = last.valueEnd
+		prefix = ",\n"
+	}
+	encoded, err := marshalJSON(value, indent)
+	if err != nil {
+		return nil, err
+	}
+	name, _ := marshalJSON(key, "")
+	entry := prefix + indent + name + ": " + encoded
+	if len(spans) == 0 {
+		entry += "\n"
+	}
+	return splice(fm, at, at, entry), nil
+}
+
+func jsonIndent(fm []byte, keyStart int) string {
+	lineStart := bytes.LastIndexByte(fm[:keyStart], '\n') + 1
+	return lineIndent(string(fm[lineStart:keyStart]))
+}
+
+func marshalJSON(value interface{}, indent string) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if _, isMap := normaliseValue(value).(map[string]interface{}); isMap {
+		encoder.SetIndent(indent, "  ")
+	}
+	if err := encoder.Encode(normaliseValue(value)); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+func splice(data []byte, from, to int, insert string) []byte {
+	edited := append([]byte{}, data[:from]...)
+	edited = append(edited, insert...)
+	return append(edited, data[to:]...)
+}
+
+// Shared helpers
+
+// normaliseValue converts values into the small set of types the encoders
+// understand: string, bool, int, float64, []interface{} and maps.
+func normaliseValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []string:
+		return toInterfaces(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case int64:
+		return int(v)
+	case float32:
+		return float64(v)
+	}
+	return value
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,455 @@
+package hugo
+
+import (
+	"reflect"
+	"testing"
+)
+
+const yamlFrontMatter = `---
+# A comment at the top
+title: "Old title" # keep me
+date: 2021-01-15
+tags:
+  - go
+  - hugo
+unknown: {a: 1}
+draft: true
+description: |
+  line one
+  line two
+author: me
+---
+`
+
+const tomlFrontMatter = `+++
+# A comment at the top
+title = "Old title" # keep me
+date = 2021-02-01
+tags = [
+  "go",
+  "hugo",
+]
+draft = true
+author = 'me'
+
+[params]
+  mood = "happy"
+  [params.nested]
+  deep = 1
+
+[[menu.main]]
+name = "Home"
++++
+`
+
+const jsonFrontMatter = `{
+  "title": "Old title",
+  "date": "2020-12-01",
+  "tags": ["go", "hugo"],
+  "draft": true,
+  "unknown": {"a": 1}
+}`
+
+const orgFrontMatter = `#+TITLE: Old title
+#+DATE: <2021-03-04 Thu>
+# a comment
+#+tags[]: emacs org
+#+draft: true
+#+author: me
+`
+
+func TestSetFrontMatter(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		fm     string
+		key    string
+		value  interface{}
+		want   string
+	}{
+		{
+			name: "yaml change keeps quotes and comment", format: YAML, fm: yamlFrontMatter,
+			key: "Title", value: "New: title",
+			want: `---
+# A comment at the top
+title: "New: title" # keep me
+date: 2021-01-15
+tags:
+  - go
+  - hugo
+unknown: {a: 1}
+draft: true
+description: |
+  line one
+  line two
+author: me
+---
+`,
+		},
+		{
+			name: "yaml change keeps list style", format: YAML, fm: yamlFrontMatter,
+			key: "tags", value: []string{"go", "testing"},
+			want: `---
+# A comment at the top
+title: "Old title" # keep me
+date: 2021-01-15
+tags:
+  - go
+  - testing
+unknown: {a: 1}
+draft: true
+description: |
+  line one
+  line two
+author: me
+---
+`,
+		},
+		{
+			name: "yaml add", format: YAML, fm: yamlFrontMatter,
+			key: "slug", value: "my-post",
+			want: `---
+# A comment at the top
+title: "Old title" # keep me
+date: 2021-01-15
+tags:
+  - go
+  - hugo
+unknown: {a: 1}
+draft: true
+description: |
+  line one
+  line two
+author: me
+slug: my-post
+---
+`,
+		},
+		{
+			name: "yaml delete list", format: YAML, fm: yamlFrontMatter,
+			key: "tags", value: nil,
+			want: `---
+# A comment at the top
+title: "Old title" # keep me
+date: 2021-01-15
+unknown: {a: 1}
+draft: true
+description: |
+  line one
+  line two
+author: me
+---
+`,
+		},
+		{
+			name: "yaml delete block scalar", format: YAML, fm: yamlFrontMatter,
+			key: "description", value: nil,
+			want: `---
+# A comment at the top
+title: "Old title" # keep me
+date: 2021-01-15
+tags:
+  - go
+  - hugo
+unknown: {a: 1}
+draft: true
+author: me
+---
+`,
+		},
+		{
+			name: "toml change keeps comment", format: TOML, fm: tomlFrontMatter,
+			key: "title", value: "New title",
+			want: `+++
+# A comment at the top
+title = "New title" # keep me
+date = 2021-02-01
+tags = [
+  "go",
+  "hugo",
+]
+draft = true
+author = 'me'
+
+[params]
+  mood = "happy"
+  [params.nested]
+  deep = 1
+
+[[menu.main]]
+name = "Home"
++++
+`,
+		},
+		{
+			name: "toml change multi-line array", format: TOML, fm: tomlFrontMatter,
+			key: "tags", value: []string{"go"},
+			want: `+++
+# A comment at the top
+title = "Old title" # keep me
+date = 2021-02-01
+tags = ["go"]
+draft = true
+author = 'me'
+
+[params]
+  mood = "happy"
+  [params.nested]
+  deep = 1
+
+[[menu.main]]
+name = "Home"
++++
+`,
+		},
+		{
+			name: "toml add goes before the tables", format: TOML, fm: tomlFrontMatter,
+			key: "publishDate", value: "2021-03-01T10:00:00Z",
+			want: `+++
+# A comment at the top
+title = "Old title" # keep me
+date = 2021-02-01
+tags = [
+  "go",
+  "hugo",
+]
+draft = true
+author = 'me'
+publishDate = 2021-03-01T10:00:00Z
+
+[params]
+  mood = "happy"
+  [params.nested]
+  deep = 1
+
+[[menu.main]]
+name = "Home"
++++
+`,
+		},
+		{
+			name: "toml delete", format: TOML, fm: tomlFrontMatter,
+			key: "draft", value: nil,
+			want: `+++
+# A comment at the top
+title = "Old title" # keep me
+date = 2021-02-01
+tags = [
+  "go",
+  "hugo",
+]
+author = 'me'
+
+[params]
+  mood = "happy"
+  [params.nested]
+  deep = 1
+
+[[menu.main]]
+name = "Home"
++++
+`,
+		},
+		{
+			name: "toml delete table", format: TOML, fm: tomlFrontMatter,
+			key: "params", value: nil,
+			want: `+++
+# A comment at the top
+title = "Old title" # keep me
+date = 2021-02-01
+tags = [
+  "go",
+  "hugo",
+]
+draft = true
+author = 'me'
+
+[[menu.main]]
+name = "Home"
++++
+`,
+		},
+		{
+			name: "json change", format: JSON, fm: jsonFrontMatter,
+			key: "draft", value: false,
+			want: `{
+  "title": "Old title",
+  "date": "2020-12-01",
+  "tags": ["go", "hugo"],
+  "draft": false,
+  "unknown": {"a": 1}
+}`,
+		},
+		{
+			name: "json add", format: JSON, fm: jsonFrontMatter,
+			key: "slug", value: "my-post",
+			want: `{
+  "title": "Old title",
+  "date": "2020-12-01",
+  "tags": ["go", "hugo"],
+  "draft": true,
+  "unknown": {"a": 1},
+  "slug": "my-post"
+}`,
+		},
+		{
+			name: "json delete", format: JSON, fm: jsonFrontMatter,
+			key: "tags", value: nil,
+			want: `{
+  "title": "Old title",
+  "date": "2020-12-01",
+  "draft": true,
+  "unknown": {"a": 1}
+}`,
+		},
+		{
+			name: "json delete first", format: JSON, fm: jsonFrontMatter,
+			key: "title", value: nil,
+			want: `{
+  "date": "2020-12-01",
+  "tags": ["go", "hugo"],
+  "draft": true,
+  "unknown": {"a": 1}
+}`,
+		},
+		{
+			name: "org change keeps timestamp", format: Org, fm: orgFrontMatter,
+			key: "date", value: "2021-04-01T00:00:00Z",
+			want: `#+TITLE: Old title
+#+DATE: <2021-04-01 Thu>
+# a comment
+#+tags[]: emacs org
+#+draft: true
+#+author: me
+`,
+		},
+		{
+			name: "org change list", format: Org, fm: orgFrontMatter,
+			key: "tags", value: []string{"emacs"},
+			want: `#+TITLE: Old title
+#+DATE: <2021-03-04 Thu>
+# a comment
+#+tags[]: emacs
+#+draft: true
+#+author: me
+`,
+		},
+		{
+			name: "org delete", format: Org, fm: orgFrontMatter,
+			key: "draft", value: nil,
+			want: `#+TITLE: Old title
+#+DATE: <2021-03-04 Thu>
+# a comment
+#+tags[]: emacs org
+#+author: me
+`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := setFrontMatter(test.format, []byte(test.fm), test.key, test.value)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if string(got) != test.want {
+				t.Errorf("got\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+// TestSetFrontMatterRoundTrip checks that a value written for every format is
+// read back unchanged, and that the other keys are not affected.
+func TestSetFrontMatterRoundTrip(t *testing.T) {
+	values := map[string]interface{}{
+		"title":  "Quotes \"and\" colons: # too",
+		"tags":   []interface{}{"a, b", "c"},
+		"draft":  false,
+		"weight": 7,
+	}
+	for format, fm := range map[Format]string{
+		YAML: yamlFrontMatter,
+		TOML: tomlFrontMatter,
+		JSON: jsonFrontMatter,
+		Org:  orgFrontMatter,
+	} {
+		before, err := parseFrontMatter(format, []byte(fm))
+		if err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+		edited := []byte(fm)
+		for _, key := range sortedKeys(values) {
+			if format == Org && key == "tags" {
+				// Org lists are separated by spaces, so items cannot
+				// hold commas and spaces.
+				continue
+			}
+			if edited, err = setFrontMatter(format, edited, key, values[key]); err != nil {
+				t.Fatalf("%s: setting %s: %s", format, key, err)
+			}
+		}
+		after, err := parseFrontMatter(format, edited)
+		if err != nil {
+			t.Fatalf("%s: %s\n%s", format, err, edited)
+		}
+		for key, value := range after {
+			want, changed := values[key]
+			if !changed {
+				want = before[key]
+			}
+			if n, ok := want.(int); ok && format == JSON {
+				want = float64(n)
+			}
+			if format == Org {
+				want = toOrgValue(want)
+			}
+			if format == Org && key == "tags" {
+				want = before[key]
+			}
+			if !reflect.DeepEqual(value, want) {
+				t.Errorf("%s: %s is %#v, want %#v", format, key, value, want)
+			}
+		}
+	}
+}
+
+// toOrgValue returns a value the way it is read back from org front matter,
+// where everything but a list is a string.
+func toOrgValue(value interface{}) interface{} {
+	if _, ok := value.([]interface{}); ok {
+		return value
+	}
+	return toString(value)
+}
+
+// TestSetYAMLBlankLines checks that a value with blank lines inside it is
+// replaced as a whole, and that the blank line after it is kept.
+func TestSetYAMLBlankLines(t *testing.T) {
+	const fm = "---\ndescription: |\n  one\n\n  two\ntags:\n  - a\n\n  - b\n\nauthor: me\n---\n"
+	tests := []struct {
+		key   string
+		value interface{}
+		want  string
+	}{
+		{"description", "new", "---\ndescription: new\ntags:\n  - a\n\n  - b\n\nauthor: me\n---\n"},
+		{"tags", []string{"c"}, "---\ndescription: |\n  one\n\n  two\ntags:\n  - c\n\nauthor: me\n---\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			got, err := setFrontMatter(YAML, []byte(fm), test.key, test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("got\n%s\nwant\n%s", got, test.want)
+			}
+			values, err := parseFrontMatter(YAML, got)
+			if err != nil {
+				t.Fatalf("the result is not valid YAML: %s\n%s", err, got)
+			}
+			if values["author"] != "me" {
+				t.Errorf("author is %#v", values["author"])
+			}
+		})
+	}
+}
@@ -0,0 +1,298 @@
+// Package index is a small full-text search index that is kept on disk between
+// runs. Documents are identified by a string, such as a file path, and the
+// modification time, size and hash of every document are stored so that only
+// the documents that changed need to be indexed again. Results are ranked with
+// BM25.
+package index
+
+import (
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// version is bumped whenever the stored format or the tokenizer changes, so
+// that old indexes are rebuilt.
+const version = 1
+
+// BM25 parameters.
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// A Document is the stored information about one indexed document.
+type Document struct {
+	ModTime time.Time
+	Size    int64
+	Hash    string
+	// Length is the number of tokens in the document, Terms its distinct
+	// tokens.
+	Length int
+	Terms  []string
+}
+
+// An Index maps every token to the documents that contain it and how often.
+type Index struct {
+	Version  int
+	Docs     map[string]Document
+	Postings map[string]map[string]int
+	// Length is the total number of tokens in all documents.
+	Length int
+
+	path    string
+	changed bool
+	sorted  []string // the tokens in Postings, sorted, for prefix matching
+}
+
+// A Result is a document that matched a search, with its score. Higher scores
+// are better matches.
+type Result struct {
+	ID    string
+	Score float64
+}
+
+// New returns an empty index that is saved to path. An empty path gives an
+// index that is only kept in memory.
+func New(path string) *Index {
+	return &Index{
+		Version:  version,
+		Docs:     make(map[string]Document),
+		Postings: make(map[string]map[string]int),
+		path:     path,
+	}
+}
+
+// Open loads the index stored at path. If there is no index there yet, or it
+// was written by another version of this package, an empty index is returned
+// which will be saved to path.
+func Open(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return New(path), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ix := New(path)
+	if err := gob.NewDecoder(f).Decode(ix); err != nil || ix.Version != version {
+		return New(path), nil
+	}
+	return ix, nil
+}
+
+// Save writes the index to its path if it has changed since it was opened.
+// The file is replaced in one go, so a crash never leaves half an index.
+func (ix *Index) Save() error {
+	if ix.path == "" || !ix.changed {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(ix.path), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(ix.path), filepath.Base(ix.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(tmp).Encode(ix); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), ix.path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	ix.changed = false
+	return nil
+}
+
+// Current reports whether the document is indexed with the given modification
+// time and size, in which case it does not need to be read again.
+func (ix *Index) Current(id string, modTime time.Time, size int64) bool {
+	doc, ok := ix.Docs[id]
+	return ok && doc.ModTime.Equal(modTime) && doc.Size == size
+}
+
+// Update indexes the content of a document, replacing what was indexed for it
+// before. If the content has not changed since, only its modification time is
+// updated. It reports whether the document was indexed again.
+func (ix *Index) Update(id string, content []byte, modTime time.Time) bool {
+	sum := sha1.Sum(content)
+	hash := hex.EncodeToString(sum[:])
+	size := int64(len(content))
+	if doc, ok := ix.Docs[id]; ok && doc.Hash == hash {
+		if !doc.ModTime.Equal(modTime) || doc.Size != size {
+			doc.ModTime, doc.Size = modTime, size
+			ix.Docs[id] = doc
+			ix.changed = true
+		}
+		return false
+	}
+
+	ix.Remove(id)
+	tokens := Tokenize(string(content))
+	counts := make(map[string]int)
+	for _, token := range tokens {
+		counts[token]++
+	}
+	doc := Document{ModTime: modTime, Size: size, Hash: hash, Length: len(tokens)}
+	for token, count := range counts {
+		postings, ok := ix.Postings[token]
+		if !ok {
+			postings = make(map[string]int)
+			ix.Postings[token] = postings
+			ix.sorted = nil
+		}
+		postings[id] = count
+		doc.Terms = append(doc.Terms, token)
+	}
+	ix.Docs[id] = doc
+	ix.Length += doc.Length
+	ix.changed = true
+	return true
+}
+
+// Remove drops a document from the index.
+func (ix *Index) Remove(id string) {
+	doc, ok := ix.Docs[id]
+	if !ok {
+		return
+	}
+	for _, token := range doc.Terms {
+		delete(ix.Postings[token], id)
+		if len(ix.Postings[token]) == 0 {
+			delete(ix.Postings, token)
+			ix.sorted = nil
+		}
+	}
+	delete(ix.Docs, id)
+	ix.Length -= doc.Length
+	ix.changed = true
+}
+
+// IDs returns the identifiers of all the indexed documents, sorted.
+func (ix *Index) IDs() []string {
+	ids := make([]string, 0, len(ix.Docs))
+	for id := range ix.Docs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Search returns the documents that contain every word of the query, best
+// matches first. A word ending in `*` matches any token that starts with it,
+// e.g. `gorout*` matches "goroutine" and "goroutines".
+func (ix *Index) Search(query string) []Result {
+	words := strings.Fields(query)
+	if len(words) == 0 || len(ix.Docs) == 0 {
+		return nil
+	}
+
+	var scores map[string]float64
+	for _, word := range words {
+		prefix := strings.HasSuffix(word, "*")
+		tokens := Tokenize(strings.TrimSuffix(word, "*"))
+		if len(tokens) == 0 {
+			continue
+		}
+		wordScores := make(map[string]float64)
+		for _, token := range tokens {
+			for _, expanded := range ix.expand(token, prefix) {
+				for id, score := range ix.score(expanded) {
+					if score > wordScores[id] {
+						wordScores[id] = score
+					}
+				}
+			}
+		}
+
+		// Keep the documents that matched every word so far.
+		if scores == nil {
+			scores = wordScores
+			continue
+		}
+		for id := range scores {
+			if score, ok := wordScores[id]; ok {
+				scores[id] += score
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for id, score := range scores {
+		results = append(results, Result{ID: id, Score: score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+	return results
+}
+
+// expand returns the tokens a search word stands for: the token itself, or
+// every indexed token that starts with it for a prefix search.
+func (ix *Index) expand(token string, prefix bool) []string {
+	if !prefix {
+		return []string{token}
+	}
+	if ix.sorted == nil {
+		ix.sorted = make([]string, 0, len(ix.Postings))
+		for t := range ix.Postings {
+			ix.sorted = append(ix.sorted, t)
+		}
+		sort.Strings(ix.sorted)
+	}
+	var tokens []string
+	for i := sort.SearchStrings(ix.sorted, token); i < len(ix.sorted) && strings.HasPrefix(ix.sorted[i], token); i++ {
+		tokens = append(tokens, ix.sorted[i])
+	}
+	return tokens
+}
+
+// score returns the BM25 score of a token for every document containing it.
+func (ix *Index) score(token string) map[string]float64 {
+	postings := ix.Postings[token]
+	if len(postings) == 0 {
+		return nil
+	}
+	n := float64(len(ix.Docs))
+	df := float64(len(postings))
+	idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+
+	avgLength := float64(ix.Length) / n
+
+	scores := make(map[string]float64, len(postings))
+	for id, count := range postings {
+		tf := float64(count)
+		length := float64(ix.Docs[id].Length)
+		scores[id] = idf * tf * (k1 + 1) / (tf + k1*(1-b+b*length/avgLength))
+	}
+	return scores
+}
+
+// Tokenize splits text into lowercase words made of letters and digits.
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,120 @@
+package index
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var modTime = time.Date(2021, 1, 15, 10, 0, 0, 0, time.UTC)
+
+func newTestIndex(path string, docs map[string]string) *Index {
+	ix := New(path)
+	for id, content := range docs {
+		ix.Update(id, []byte(content), modTime)
+	}
+	return ix
+}
+
+func ids(results []Result) []string {
+	var ids []string
+	for _, result := range results {
+		ids = append(ids, result.ID)
+	}
+	return ids
+}
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("Hello, World! Ça va? go1.16 → 世界")
+	want := []string{"hello", "world", "ça", "va", "go1", "16", "世界"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	ix := newTestIndex("", map[string]string{
+		"once":    "go is a language with a long description of many other things",
+		"often":   "go go go",
+		"short":   "go",
+		"nothing": "rust and zig",
+		"prefix":  "goroutines and gophers",
+		"both":    "go channels",
+	})
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// Short documents and documents with the word more often rank first.
+		{"go", []string{"often", "short", "both", "once"}},
+		{"GO channels", []string{"both"}},
+		{"gorout*", []string{"prefix"}},
+		// A prefix scores as its best match, and rare words score higher.
+		{"go*", []string{"prefix", "often", "short", "both", "once"}},
+		{"go zig", nil},
+		{"", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			if got := ids(ix.Search(test.query)); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUpdateAndRemove(t *testing.T) {
+	ix := newTestIndex("", map[string]string{"a": "apples and pears", "b": "pears"})
+	if ix.Update("a", []byte("apples and pears"), modTime.Add(time.Hour)) {
+		t.Error("a document with the same content was indexed again")
+	}
+	if !ix.Current("a", modTime.Add(time.Hour), 16) {
+		t.Error("the new modification time was not stored")
+	}
+	if !ix.Update("a", []byte("plums"), modTime) {
+		t.Error("a changed document was not indexed again")
+	}
+	if got := ids(ix.Search("apples")); got != nil {
+		t.Errorf("the old content is still found in %q", got)
+	}
+
+	ix.Remove("b")
+	ix.Remove("missing")
+	if got := ids(ix.Search("pears")); got != nil {
+		t.Errorf("a removed document is still found in %q", got)
+	}
+	if _, ok := ix.Postings["pears"]; ok {
+		t.Error("a token with no documents left is still in the index")
+	}
+	if !reflect.DeepEqual(ix.IDs(), []string{"a"}) || ix.Length != 1 {
+		t.Errorf("the index holds %q with %d tokens", ix.IDs(), ix.Length)
+	}
+}
+
+func TestSaveOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "index.gob")
+	ix := newTestIndex(path, map[string]string{"a": "apples and pears", "b": "pears"})
+	if err := ix.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	opened, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(opened.Docs, ix.Docs) || !reflect.DeepEqual(opened.Postings, ix.Postings) || opened.Length != ix.Length {
+		t.Error("the opened index differs from the saved one")
+	}
+	if !reflect.DeepEqual(ids(opened.Search("pear*")), ids(ix.Search("pear*"))) {
+		t.Error("the opened index finds other documents")
+	}
+	if !opened.Current("a", modTime, 16) {
+		t.Error("the opened index does not know the modification time")
+	}
+
+	missing, err := Open(filepath.Join(t.TempDir(), "missing.gob"))
+	if err != nil || len(missing.Docs) != 0 {
+		t.Errorf("opening a missing index gave %v, %v", missing, err)
+	}
+}
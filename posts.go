@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sudosays/hydra/pkg/data/hugo"
+)
+
+const addUsage = "usage: a [--section <name>] [--kind <archetype>] [--bundle] [title]"
+
+// addOptions parses the arguments of the add command. The title is everything
+// after the flags. Posts go in the default section of the site unless another
+// one is given.
+func addOptions(args []string) (hugo.PostOptions, error) {
+	site := config.Sites[activeSite]
+	opts := hugo.PostOptions{
+		Extension: config.Extension,
+		Section:   site.Section,
+		Slug: hugo.SlugOptions{
+			MaxLength:  site.Slug.MaxLength,
+			DatePrefix: site.Slug.DatePrefix,
+			Suffix:     site.Slug.OnCollision == "suffix",
+		},
+	}
+	for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+		switch args[0] {
+		case "--bundle":
+			opts.Bundle = true
+		case "--section", "--kind":
+			if len(args) < 2 {
+				return opts, fmt.Errorf("%s needs a value, %s", args[0], addUsage)
+			}
+			if args[0] == "--section" {
+				opts.Section = args[1]
+			} else {
+				opts.Kind = args[1]
+			}
+			args = args[1:]
+		default:
+			return opts, fmt.Errorf("unknown option %s, %s", args[0], addUsage)
+		}
+		args = args[1:]
+	}
+	opts.Title = strings.Join(args, " ")
+	return opts, nil
+}
+
+// movePost handles `mv <n> <section>` and `rename <n> <name>`.
+func movePost(parts []string, blog *hugo.Blog) error {
+	if len(parts) < 3 {
+		if parts[0] == "mv" || parts[0] == "move" {
+			return fmt.Errorf("usage: mv <n> <section>")
+		}
+		return fmt.Errorf("usage: rename <n> <name>")
+	}
+	post, err := promptPost(parts, "", *blog)
+	if err != nil {
+		return err
+	}
+
+	var newPath string
+	if parts[0] == "mv" || parts[0] == "move" {
+		newPath, err = blog.MovePost(post.Path, parts[2])
+	} else {
+		newPath, err = blog.RenamePost(post.Path, parts[2])
+	}
+	if err != nil {
+		return err
+	}
+	statusMessage = fmt.Sprintf("Moved '%s' to %s", post.Title, newPath)
+	return nil
+}
+
+// resourceList lists the resources of a page bundle.
+func resourceList(post hugo.Post) (string, error) {
+	if !post.Bundle {
+		return "", fmt.Errorf("'%s' is not a page bundle", post.Title)
+	}
+	if len(post.Resources) == 0 {
+		return "", fmt.Errorf("the bundle of '%s' has no resources", post.Title)
+	}
+	var listing strings.Builder
+	fmt.Fprintf(&listing, "Resources of '%s' (%s):\n", post.Title, post.Path)
+	for _, resource := range post.Resources {
+		fmt.Fprintf(&listing, "  %s\n", resource)
+	}
+	return listing.String(), nil
+}
+
+// sectionList lists the sections of the site with the number of posts in
+// each, and the archetypes that can be used with `a --kind`.
+func sectionList(blog hugo.Blog) (string, error) {
+	sections, err := blog.Sections()
+	if err != nil {
+		return "", err
+	}
+	kinds, err := blog.Archetypes()
+	if err != nil {
+		return "", err
+	}
+
+	counts := make(map[string]int)
+	for _, post := range blog.Posts {
+		counts[post.Section]++
+	}
+	defaultSection := config.Sites[activeSite].Section
+	if defaultSection == "" {
+		defaultSection = "blog"
+	}
+
+	var listing strings.Builder
+	listing.WriteString("Sections:\n")
+	for _, section := range sections {
+		marker := " "
+		if section == defaultSection {
+			marker = "*"
+		}
+		fmt.Fprintf(&listing, "%s %s\t%d\n", marker, section, counts[section])
+	}
+	listing.WriteString("\nArchetypes:\n")
+	if len(kinds) == 0 {
+		listing.WriteString("  none, Hugo's default archetype is used\n")
+	}
+	for _, kind := range kinds {
+		fmt.Fprintf(&listing, "  %s\n", kind)
+	}
+	return listing.String(), nil
+}
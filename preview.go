@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sudosays/hydra/pkg/data/hugo"
+)
+
+const previewUsage = "usage: preview [start] [--port <n>] [--drafts] [--future] | preview stop | preview status | preview log"
+
+// previewLogLines is how much of the log of the server `preview log` shows.
+const previewLogLines = 20
+
+// preview is the `hugo server` previewing the active site, if one was started.
+// It is kept after the server exits so that the reason can be shown.
+var preview *hugo.Server
+
+// previewCommand handles the commands of the preview server. Without a
+// subcommand it starts the server, or shows its status if it is running.
+func previewCommand(args []string, blog hugo.Blog) (string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		if previewRunning() {
+			return previewStatus() + "\n", nil
+		}
+		args = append([]string{"start"}, args...)
+	}
+
+	switch args[0] {
+	case "start":
+		opts, err := previewOptions(args[1:])
+		if err != nil {
+			return "", err
+		}
+		if err := startPreview(blog, opts); err != nil {
+			return "", err
+		}
+		statusMessage = previewStatus()
+	case "stop":
+		if !previewRunning() {
+			return "", fmt.Errorf("the preview is not running")
+		}
+		stopPreview()
+		statusMessage = "Stopped the preview"
+	case "status":
+		return previewStatus() + "\n", nil
+	case "log":
+		if preview == nil {
+			return "", fmt.Errorf("the preview has not been started")
+		}
+		return previewStatus() + "\n\n" + strings.Join(preview.Log(previewLogLines), "\n") + "\n", nil
+	default:
+		return "", fmt.Errorf(previewUsage)
+	}
+	return "", nil
+}
+
+// previewOptions reads the options of `preview start`, starting from the
+// preview config of the active site.
+func previewOptions(args []string) (hugo.ServerOptions, error) {
+	site := config.Sites[activeSite]
+	opts := hugo.ServerOptions{
+		Port:   site.Preview.Port,
+		Drafts: site.Preview.Drafts,
+		Future: site.Preview.Future,
+	}
+	for len(args) > 0 {
+		switch args[0] {
+		case "--drafts", "-D":
+			opts.Drafts = true
+		case "--future", "-F":
+			opts.Future = true
+		case "--port", "-p":
+			if len(args) < 2 {
+				return opts, fmt.Errorf("%s needs a value, %s", args[0], previewUsage)
+			}
+			port, err := strconv.Atoi(args[1])
+			if err != nil || port < 1 || port > 65535 {
+				return opts, fmt.Errorf("'%s' is not a valid port", args[1])
+			}
+			opts.Port = port
+			args = args[1:]
+		default:
+			return opts, fmt.Errorf("unknown option %s, %s", args[0], previewUsage)
+		}
+		args = args[1:]
+	}
+	return opts, nil
+}
+
+// startPreview starts `hugo server` for blog, replacing the previous preview.
+func startPreview(blog hugo.Blog, opts hugo.ServerOptions) error {
+	stopPreview()
+	server, err := blog.StartServer(opts)
+	if err != nil {
+		return err
+	}
+	preview = server
+	return nil
+}
+
+// stopPreview stops the preview server if it is running. It is called when
+// hydra quits or another site is opened.
+func stopPreview() {
+	if preview != nil {
+		preview.Stop()
+		preview = nil
+	}
+}
+
+func previewRunning() bool {
+	return preview != nil && preview.Running()
+}
+
+// previewStatus describes the state of the preview server in one line.
+func previewStatus() string {
+	if preview == nil {
+		return "The preview is not running"
+	}
+	if !preview.Running() {
+		if err := preview.Err(); err != nil {
+			return fmt.Sprintf("The preview stopped: %s", err)
+		}
+		return "The preview stopped"
+	}
+
+	var extras []string
+	if preview.Options.Drafts {
+		extras = append(extras, "drafts")
+	}
+	if preview.Options.Future {
+		extras = append(extras, "future posts")
+	}
+	status := "Previewing at " + preview.URL()
+	if len(extras) > 0 {
+		status += " with " + strings.Join(extras, " and ")
+	}
+	return status
+}
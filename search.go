@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/sudosays/hydra/pkg/data/hugo"
+)
+
+// snippetWidth is the most of a matching line shown in search results.
+const snippetWidth = 72
+
+// A searchHit is one numbered entry in the search results: a matching line,
+// or a post that matched on its fields alone (Line 0).
+type searchHit struct {
+	path string
+	line int
+}
+
+// searchCommand runs a search and lists the results, numbering every matching
+// line so it can be opened in the editor.
+func searchCommand(search string, blog hugo.Blog) (string, []searchHit, error) {
+	if search == "" {
+		return "", nil, fmt.Errorf("usage: search <text|/regexp/> [field:value ...]")
+	}
+	results, err := blog.Search(search)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(results) == 0 {
+		return "", nil, fmt.Errorf("no posts match '%s'", search)
+	}
+
+	var listing strings.Builder
+	var hits []searchHit
+	for _, result := range results {
+		fmt.Fprintf(&listing, "#%d %s (%s)\n", result.Index+1, result.Post.Title, result.Post.Path)
+		if len(result.Matches) == 0 {
+			hits = append(hits, searchHit{path: result.Post.Path})
+			fmt.Fprintf(&listing, "  [%d]\n", len(hits))
+		}
+		for _, match := range result.Matches {
+			hits = append(hits, searchHit{path: result.Post.Path, line: match.Line})
+			fmt.Fprintf(&listing, "  [%d] %4d: %s\n", len(hits), match.Line, snippet(match))
+		}
+	}
+	return listing.String(), hits, nil
+}
+
+// snippet shortens the line of a match to fit on screen, keeping the match in
+// view, and highlights the match.
+func snippet(match hugo.Match) string {
+	text, start, end := match.Text, match.Start, match.End
+	if len(text) > snippetWidth {
+		from := start - (snippetWidth-(end-start))/2
+		if from < 0 {
+			from = 0
+		}
+		to := from + snippetWidth
+		if to < end {
+			to = end
+		}
+		if to > len(text) {
+			to = len(text)
+		}
+		// Cut on rune boundaries only.
+		for from > 0 && !isRuneStart(text[from]) {
+			from--
+		}
+		for to < len(text) && !isRuneStart(text[to]) {
+			to++
+		}
+		prefix, suffix := "", ""
+		if from > 0 {
+			prefix = "…"
+		}
+		if to < len(text) {
+			suffix = "…"
+		}
+		text = prefix + text[from:to] + suffix
+		start, end = start-from+len(prefix), end-from+len(prefix)
+	}
+	text = strings.ReplaceAll(text, "\t", " ")
+	return text[:start] + "\033[7m" + text[start:end] + "\033[0m" + text[end:]
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// openSearchHit asks for the number of a search result and opens it in the
+// editor at the matching line.
+func openSearchHit(hits []searchHit, blog *hugo.Blog) error {
+	ans := strings.TrimSpace(promptUser("Enter a result number to open it, or press Enter to return to the post list\n> "))
+	if ans == "" {
+		return nil
+	}
+	i, err := strconv.Atoi(ans)
+	if err != nil || i < 1 || i > len(hits) {
+		return fmt.Errorf("there is no search result number %s", ans)
+	}
+	if err := startEditorAt(filepath.Join(blog.Path, hits[i-1].path), hits[i-1].line); err != nil {
+		return err
+	}
+	return blog.Refresh(hits[i-1].path)
+}
+
+// saveIndex writes the search index of the site after a command has run, so
+// that it is saved once however many posts the command changed.
+func saveIndex(blog hugo.Blog) {
+	if err := blog.SaveIndex(); err != nil {
+		statusMessage = fmt.Sprintf("Saving the search index failed: %s", err)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sudosays/hydra/pkg/data/hugo"
+)
+
+// hydraState is remembered between runs of hydra.
+type hydraState struct {
+	Site string `json:"site"`
+}
+
+var statePath string
+
+// activeSite is the index in config.Sites of the site being managed.
+var activeSite int
+
+// readState reads what was remembered from the last run. A missing state file
+// is not an error, hydra has just not been run yet.
+func readState() (hydraState, error) {
+	state := hydraState{}
+	data, err := ioutil.ReadFile(statePath)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("%s is not valid JSON: %w", statePath, err)
+	}
+	return state, nil
+}
+
+func writeState(state hydraState) error {
+	data, err := json.MarshalIndent(state, "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(statePath, data, 0644)
+}
+
+// findSite looks up a site in the config by its number in the site list
+// (starting at 1) or by its name.
+func findSite(nameOrIndex string) (int, error) {
+	if i, err := strconv.Atoi(nameOrIndex); err == nil {
+		if i < 1 || i > len(config.Sites) {
+			return -1, fmt.Errorf("there is no site number %d", i)
+		}
+		return i - 1, nil
+	}
+	for i, site := range config.Sites {
+		if strings.EqualFold(site.Name, nameOrIndex) {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("there is no site called '%s'", nameOrIndex)
+}
+
+// initialSite picks the site to open on start up: the one given on the
+// command line, otherwise the one used last time, otherwise the first.
+func initialSite(flagValue string) (int, error) {
+	if flagValue != "" {
+		return findSite(flagValue)
+	}
+	state, err := readState()
+	if err != nil {
+		statusMessage = fmt.Sprintf("Could not read the last used site: %s", err)
+	}
+	if state.Site != "" {
+		if i, err := findSite(state.Site); err == nil {
+			return i, nil
+		}
+	}
+	return 0, nil
+}
+
+// switchSite loads the site at index i and makes it the active one. If the
+// site cannot be loaded the active site does not change. Failing to remember
+// the site for the next run is reported in the status message.
+func switchSite(i int) (hugo.Blog, error) {
+	blog, err := loadSite(config.Sites[i])
+	if err != nil {
+		return blog, err
+	}
+	if i != activeSite {
+		stopPreview()
+	}
+	activeSite = i
+	view = listView{sortKey: "date", desc: true}
+	currentPageIndex = 1
+	if err := writeState(hydraState{Site: config.Sites[i].Name}); err != nil {
+		statusMessage = fmt.Sprintf("Could not remember %s as the last used site: %s", config.Sites[i].Name, err)
+	}
+	watchSite(blog)
+	return blog, nil
+}
+
+func siteList() string {
+	var list strings.Builder
+	for i, site := range config.Sites {
+		marker := " "
+		if i == activeSite {
+			marker = "*"
+		}
+		fmt.Fprintf(&list, "%s %d\t%s\t%s\n", marker, i+1, site.Name, site.Path)
+	}
+	return list.String()
+}
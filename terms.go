@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sudosays/hydra/pkg/data/hugo"
+	"github.com/sudosays/hydra/pkg/data/hugo/taxonomy"
+)
+
+const taxUsage = "usage: tax [<taxonomy> [term]] | tax rename|merge <taxonomy> <term> <term> | tax delete <taxonomy> <term>"
+
+// taxCommand handles the taxonomy manager commands. Listings are returned as
+// text to show to the user, changes are reported in the status message.
+func taxCommand(args []string, blog *hugo.Blog) (string, error) {
+	if len(args) == 0 {
+		if err := taxonomy.Check(*blog); err != nil {
+			return "", err
+		}
+		var listing strings.Builder
+		index := taxonomy.Build(*blog)
+		for _, name := range taxonomy.Taxonomies(*blog) {
+			fmt.Fprintf(&listing, "%s:\n%s\n", strings.Title(name), termList(index[name]))
+		}
+		return listing.String(), nil
+	}
+
+	switch args[0] {
+	case "rename", "merge", "delete":
+		return "", changeTerms(args, blog)
+	}
+
+	name, err := taxonomy.Resolve(*blog, args[0])
+	if err != nil {
+		return "", err
+	}
+	index := taxonomy.Build(*blog)
+	if len(args) == 1 {
+		return termList(index[name]), nil
+	}
+
+	term, ok := index.Find(name, args[1])
+	if !ok {
+		return "", fmt.Errorf("no posts use the %s term '%s'", name, args[1])
+	}
+	var listing strings.Builder
+	fmt.Fprintf(&listing, "Posts with the %s term '%s':\n", name, term.Name)
+	for _, i := range term.Posts {
+		fmt.Fprintf(&listing, "%d\t%s\n", i+1, blog.Posts[i].Title)
+	}
+	return listing.String(), nil
+}
+
+func changeTerms(args []string, blog *hugo.Blog) error {
+	if len(args) < 3 || args[0] != "delete" && len(args) < 4 {
+		return fmt.Errorf(taxUsage)
+	}
+	name, err := taxonomy.Resolve(*blog, args[1])
+	if err != nil {
+		return err
+	}
+
+	changed := 0
+	switch args[0] {
+	case "rename":
+		changed, err = taxonomy.Rename(blog, name, args[2], args[3])
+	case "merge":
+		changed, err = taxonomy.Merge(blog, name, args[2], args[3])
+	case "delete":
+		warn := "You are about to remove the %s term '%s' from every post.\nProceed? [y/N] Default: N.\n> "
+		if !confirmed(promptUser(fmt.Sprintf(warn, name, args[2]))) {
+			return nil
+		}
+		changed, err = taxonomy.Delete(blog, name, args[2])
+	}
+	if err != nil {
+		return err
+	}
+	statusMessage = fmt.Sprintf("Updated %d posts", changed)
+	return nil
+}
+
+func termList(terms []taxonomy.Term) string {
+	if len(terms) == 0 {
+		return "  (none)\n"
+	}
+	var list strings.Builder
+	for _, term := range terms {
+		fmt.Fprintf(&list, "  %-20s %d\n", term.Name, term.Count())
+	}
+	return list.String()
+}
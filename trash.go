@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sudosays/hydra/pkg/data/hugo"
+)
+
+const trashUsage = "usage: trash [list] | trash restore <n> | trash empty"
+
+// trashCommand handles the commands for deleted posts. Listings are returned
+// as text to show to the user, changes are reported in the status message.
+func trashCommand(args []string, blog *hugo.Blog) (string, error) {
+	items, err := blog.Trash()
+	if err != nil {
+		return "", err
+	}
+	if len(items) == 0 {
+		return "", fmt.Errorf("the trash is empty")
+	}
+	if len(args) == 0 || args[0] == "list" {
+		var listing strings.Builder
+		for i, item := range items {
+			fmt.Fprintf(&listing, "%d\t%s\t%s\t%s\n", i+1, item.Deleted.Format("2006/01/02 15:04"), item.Title, item.Path)
+		}
+		return listing.String(), nil
+	}
+
+	switch args[0] {
+	case "restore":
+		if len(args) < 2 {
+			return "", fmt.Errorf(trashUsage)
+		}
+		i, err := strconv.Atoi(args[1])
+		if err != nil || i < 1 || i > len(items) {
+			return "", fmt.Errorf("there is no post number %s in the trash", args[1])
+		}
+		if err := blog.RestorePost(items[i-1]); err != nil {
+			return "", err
+		}
+		statusMessage = fmt.Sprintf("Restored '%s'", items[i-1].Title)
+	case "empty":
+		warn := "You are about to permanently delete the %d posts in the trash.\nProceed? [y/N] Default: N.\n> "
+		if !confirmed(promptUser(fmt.Sprintf(warn, len(items)))) {
+			return "", nil
+		}
+		removed, err := blog.EmptyTrash()
+		if err != nil {
+			return "", err
+		}
+		statusMessage = fmt.Sprintf("Removed %d posts from the trash", removed)
+	default:
+		return "", fmt.Errorf(trashUsage)
+	}
+	return "", nil
+}
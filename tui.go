@@ -0,0 +1,292 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/sudosays/hydra/internal/ui"
+	"github.com/sudosays/hydra/pkg/data/hugo"
+)
+
+// postTUI shows the post list of a blog in a ui.Table that can be navigated
+// with the keyboard, as an alternative to the plain REPL.
+type postTUI struct {
+	ui     ui.PneumaUI
+	blog   hugo.Blog
+	header *ui.Label
+	status *ui.Label
+	table  *ui.Table
+	// log shows the output of the preview server while there is one.
+	log *ui.Pane
+}
+
+// previewPaneHeight is the height of the pane showing the log of the preview
+// server, borders included.
+const previewPaneHeight = 8
+
+func runeKey(r rune) ui.CommandKey {
+	return ui.CommandKey{Key: tcell.KeyRune, Rune: r}
+}
+
+// runTUI takes over the terminal until the user quits. It returns an error if
+// the terminal cannot be used.
+func runTUI(blog hugo.Blog) error {
+	screen, err := ui.Init()
+	if err != nil {
+		return err
+	}
+	t := &postTUI{ui: screen, blog: blog}
+	t.header = t.ui.AddLabel(0, 0, "")
+	t.status = t.ui.AddLabel(0, 1, "")
+	headings, rows := genPostList(blog)
+	t.table = t.ui.AddTable(0, 2, headings, rows)
+	t.log = t.ui.AddPane(0, 0, 0, 0, "")
+
+	t.ui.SetCommands(map[ui.CommandKey]ui.Command{
+		runeKey('j'):                          {Callback: t.table.NextItem, Description: "down"},
+		runeKey('k'):                          {Callback: t.table.PreviousItem, Description: "up"},
+		{Key: tcell.KeyEnter, Rune: rune(13)}: {Callback: t.edit, Description: "edit"},
+		runeKey('a'):                          {Callback: t.add, Description: "add"},
+		runeKey('d'):                          {Callback: t.delete, Description: "delete"},
+		runeKey('v'):                          {Callback: t.togglePreview, Description: "preview"},
+		runeKey('o'):                          {Callback: t.open, Description: "open"},
+		runeKey('s'):                          {Callback: t.sort, Description: "sort"},
+		runeKey('f'):                          {Callback: t.filter, Description: "filter"},
+		runeKey('q'):                          {Callback: t.quit, Description: "quit"},
+	})
+
+	if watcher != nil {
+		go t.watch(watcher.Changes)
+	}
+
+	t.refresh()
+	t.ui.Redraw()
+	for !t.ui.Exit {
+		t.ui.Tick()
+	}
+	return nil
+}
+
+// statusWriter shows what is written to it as the status message. Only the
+// latest message is kept, with a count of the ones before it, so that loading
+// a site with many broken posts does not overflow the status line. It is only
+// written to from the goroutine running the UI.
+type statusWriter struct{}
+
+// statusReplaced counts the messages the latest one written to statusWriter
+// replaced.
+var statusReplaced int
+
+func (statusWriter) Write(p []byte) (int, error) {
+	message := strings.TrimSpace(string(p))
+	if statusMessage != "" {
+		statusReplaced++
+		message = fmt.Sprintf("%s (and %d more)", message, statusReplaced)
+	}
+	statusMessage = message
+	return len(p), nil
+}
+
+// watch updates the table whenever the watcher finds files that changed
+// outside hydra. The posts are only touched by the goroutine running the UI.
+func (t *postTUI) watch(changes <-chan []string) {
+	for files := range changes {
+		files := files
+		t.ui.Post(func() {
+			if err := t.blog.FilesChanged(files); err != nil {
+				statusMessage = err.Error()
+			}
+			t.refresh()
+		})
+	}
+}
+
+// refresh rebuilds the table from the posts of the blog and shows the latest
+// status message. It runs after every command, which is also when the search
+// index is saved and the git status read again.
+func (t *postTUI) refresh() {
+	saveIndex(t.blog)
+	gitChanged()
+	headings, rows := genPostList(t.blog)
+	t.table.SetContent(headings, rows)
+	if t.table.Index >= len(rows) {
+		t.table.Index = len(rows) - 1
+	}
+	if t.table.Index < 0 {
+		t.table.Index = 0
+	}
+
+	t.header.Content = fmt.Sprintf("Site: %s (%s) | %s", config.Sites[activeSite].Name, t.blog.Path, view)
+	t.status.Content = statusMessage
+	statusMessage, statusReplaced = "", 0
+	t.layout()
+}
+
+// layout sizes the table to the screen, making room for the log of the
+// preview server below it if there is one.
+func (t *postTUI) layout() {
+	// Leave room for the header, the table border, the prompt and the footer.
+	w, h := t.ui.Screen.Size()
+	t.table.Height = h - t.table.Y - 6
+
+	t.log.Hidden = preview == nil || t.table.Height-previewPaneHeight < 3
+	if t.log.Hidden {
+		return
+	}
+	t.table.Height -= previewPaneHeight
+	t.log.Width, t.log.Height = w, previewPaneHeight
+	t.log.Y = h - 3 - previewPaneHeight
+	t.log.Title = previewStatus()
+	t.log.Lines = preview.Log(previewPaneHeight - 2)
+}
+
+// open opens the highlighted post in the browser.
+func (t *postTUI) open() {
+	post, ok := t.selected()
+	if !ok {
+		return
+	}
+	status, err := openPost(t.blog, post)
+	if err != nil {
+		status = err.Error()
+	}
+	statusMessage = status
+	t.refresh()
+}
+
+// togglePreview starts `hugo server` for the site, or stops it if it is
+// running. Its log is shown below the post list and kept up to date.
+func (t *postTUI) togglePreview() {
+	if previewRunning() {
+		stopPreview()
+		statusMessage = "Stopped the preview"
+		t.refresh()
+		return
+	}
+	opts, err := previewOptions(nil)
+	if err == nil {
+		opts.OnOutput = func() {
+			t.ui.Post(t.layout)
+		}
+		err = startPreview(t.blog, opts)
+	}
+	if err != nil {
+		statusMessage = err.Error()
+	}
+	t.refresh()
+}
+
+// selected returns the post on the highlighted row of the table.
+func (t *postTUI) selected() (hugo.Post, bool) {
+	if len(t.table.Content) == 0 {
+		return hugo.Post{}, false
+	}
+	n, err := strconv.Atoi(t.table.Content[t.table.Index][0])
+	if err != nil || n < 1 || n > len(t.blog.Posts) {
+		return hugo.Post{}, false
+	}
+	return t.blog.Posts[n-1], true
+}
+
+func (t *postTUI) edit() {
+	post, ok := t.selected()
+	if !ok {
+		return
+	}
+	t.ui.Suspend()
+	err := startEditor(filepath.Join(t.blog.Path, post.Path))
+	if err == nil {
+		err = t.blog.Refresh(post.Path)
+	}
+	if err != nil {
+		statusMessage = err.Error()
+	}
+	t.resume()
+}
+
+func (t *postTUI) add() {
+	title := t.ui.Prompt("Title for the new post: ")
+	if title == "" {
+		return
+	}
+	// The prompt takes the same options as the add command of the REPL.
+	opts, err := addOptions(splitArgs(title))
+	if err != nil {
+		statusMessage = err.Error()
+		t.refresh()
+		return
+	}
+	t.ui.Suspend()
+	post, err := t.blog.NewPost(opts)
+	if err == nil {
+		err = startEditor(filepath.Join(t.blog.Path, post.Path))
+	}
+	if err == nil {
+		err = t.blog.Refresh(post.Path)
+	}
+	if err != nil {
+		statusMessage = err.Error()
+	}
+	t.resume()
+}
+
+// sort asks how to sort the post list, taking the same arguments as the sort
+// command of the REPL, e.g. "title asc".
+func (t *postTUI) sort() {
+	args := t.ui.Prompt("Sort by (date, title, draft or section, then asc or desc): ")
+	if args == "" {
+		return
+	}
+	if err := view.setSort(splitArgs(args)); err != nil {
+		statusMessage = err.Error()
+	}
+	t.refresh()
+}
+
+// filter asks for a filter on the post list, taking the same arguments as the
+// filter command of the REPL, e.g. "section notes" or "clear".
+func (t *postTUI) filter() {
+	args := t.ui.Prompt("Filter (draft, published, all, title, section, from, to or clear): ")
+	if args == "" {
+		return
+	}
+	if err := view.setFilter(splitArgs(args)); err != nil {
+		statusMessage = err.Error()
+	}
+	t.refresh()
+}
+
+// resume takes the terminal back after the editor has run.
+func (t *postTUI) resume() {
+	if err := t.ui.Resume(); err != nil {
+		fatal(err)
+	}
+	t.refresh()
+}
+
+func (t *postTUI) delete() {
+	post, ok := t.selected()
+	if !ok {
+		return
+	}
+	_, h := t.ui.Screen.Size()
+	t.ui.MoveCursor(0, h-3)
+	if t.ui.Confirm(fmt.Sprintf("Delete the post titled '%s'? [y/N]", post.Title)) {
+		if err := t.blog.DeletePost(post.Path); err != nil {
+			statusMessage = err.Error()
+		} else {
+			statusMessage = fmt.Sprintf("Moved '%s' to the trash", post.Title)
+		}
+	}
+	t.refresh()
+}
+
+// quit closes the current screen, which is replaced every time the editor
+// runs, and so ends runTUI.
+func (t *postTUI) quit() {
+	saveIndex(t.blog)
+	t.ui.Close()
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sudosays/hydra/pkg/data/hugo"
+)
+
+// A listView controls which posts are shown in the post list, and in which
+// order. Posts keep their number from Blog.Posts, so a command like `e 3`
+// always targets the post shown as #3.
+type listView struct {
+	sortKey  string
+	desc     bool
+	status   string // "draft", "published" or "" for both
+	from, to time.Time
+	title    string
+	section  string
+}
+
+var sortKeys = []string{"date", "title", "draft", "section"}
+
+var view = listView{sortKey: "date", desc: true}
+
+// apply returns the indices into posts of the posts that pass the filters, in
+// the order they should be shown.
+func (v listView) apply(posts []hugo.Post) []int {
+	var indices []int
+	for i, post := range posts {
+		if v.matches(post) {
+			indices = append(indices, i)
+		}
+	}
+
+	sort.SliceStable(indices, func(a, b int) bool {
+		less, equal := v.compare(posts[indices[a]], posts[indices[b]])
+		if equal {
+			return false
+		}
+		return less != v.desc
+	})
+	return indices
+}
+
+func (v listView) matches(post hugo.Post) bool {
+	if v.status == "draft" && !post.Draft || v.status == "published" && post.Draft {
+		return false
+	}
+	if !v.from.IsZero() || !v.to.IsZero() {
+		date := postDate(post)
+		if !v.from.IsZero() && date.Before(v.from) {
+			return false
+		}
+		// The end of the range includes the whole day.
+		if !v.to.IsZero() && !date.Before(v.to.AddDate(0, 0, 1)) {
+			return false
+		}
+	}
+	if v.title != "" && !strings.Contains(strings.ToLower(post.Title), strings.ToLower(v.title)) {
+		return false
+	}
+	if v.section != "" && !strings.EqualFold(post.Section, v.section) {
+		return false
+	}
+	return true
+}
+
+// compare reports whether a sorts before b in ascending order, and whether
+// they are equal for the current sort key.
+func (v listView) compare(a, b hugo.Post) (bool, bool) {
+	switch v.sortKey {
+	case "title":
+		x, y := strings.ToLower(a.Title), strings.ToLower(b.Title)
+		return x < y, x == y
+	case "draft":
+		return !a.Draft && b.Draft, a.Draft == b.Draft
+	case "section":
+		return a.Section < b.Section, a.Section == b.Section
+	}
+	x, y := postDate(a), postDate(b)
+	return x.Before(y), x.Equal(y)
+}
+
+// setSort handles the arguments of the sort command: `s <key> [asc|desc]`.
+func (v *listView) setSort(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: s <%s> [asc|desc]", strings.Join(sortKeys, "|"))
+	}
+	key := strings.ToLower(args[0])
+	valid := false
+	for _, k := range sortKeys {
+		valid = valid || k == key
+	}
+	if !valid {
+		return fmt.Errorf("cannot sort by '%s', use one of: %s", args[0], strings.Join(sortKeys, ", "))
+	}
+
+	desc := key == "date"
+	if len(args) > 1 {
+		switch strings.ToLower(args[1]) {
+		case "asc":
+			desc = false
+		case "desc":
+			desc = true
+		default:
+			return fmt.Errorf("unknown sort direction '%s', use asc or desc", args[1])
+		}
+	}
+	v.sortKey, v.desc = key, desc
+	return nil
+}
+
+// setFilter handles the arguments of the filter command, e.g. `f draft`,
+// `f title hugo`, `f from 2021-01-01` or `f clear`.
+func (v *listView) setFilter(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: f <draft|published|all|title <text>|section <name>|from <date>|to <date>|clear>")
+	}
+	value := strings.Join(args[1:], " ")
+	switch strings.ToLower(args[0]) {
+	case "draft", "drafts":
+		v.status = "draft"
+	case "published":
+		v.status = "published"
+	case "all":
+		v.status = ""
+	case "title":
+		v.title = value
+	case "section":
+		v.section = value
+	case "from", "to":
+		var date time.Time
+		if value != "" {
+			d, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				return fmt.Errorf("dates must look like 2006-01-02: %s", value)
+			}
+			date = d
+		}
+		if strings.ToLower(args[0]) == "from" {
+			v.from = date
+		} else {
+			v.to = date
+		}
+	case "clear":
+		*v = listView{sortKey: v.sortKey, desc: v.desc}
+	default:
+		return fmt.Errorf("unknown filter '%s'", args[0])
+	}
+	return nil
+}
+
+// String describes the sorting and any active filters.
+func (v listView) String() string {
+	direction := "asc"
+	if v.desc {
+		direction = "desc"
+	}
+	desc := fmt.Sprintf("Sorted by %s %s", v.sortKey, direction)
+
+	var filters []string
+	if v.status != "" {
+		filters = append(filters, v.status)
+	}
+	if v.title != "" {
+		filters = append(filters, fmt.Sprintf("title contains '%s'", v.title))
+	}
+	if v.section != "" {
+		filters = append(filters, "section "+v.section)
+	}
+	if !v.from.IsZero() {
+		filters = append(filters, "from "+v.from.Format("2006/01/02"))
+	}
+	if !v.to.IsZero() {
+		filters = append(filters, "to "+v.to.Format("2006/01/02"))
+	}
+	if len(filters) > 0 {
+		desc += " | Filter: " + strings.Join(filters, ", ")
+	}
+	return desc
+}
+
+func postDate(post hugo.Post) time.Time {
+	date, _ := time.Parse(time.RFC3339, post.Date)
+	return date
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sudosays/hydra/internal/watch"
+	"github.com/sudosays/hydra/pkg/data/hugo"
+)
+
+// watchInterval is how often the content directory is checked for changes.
+// Changes are applied once a check finds nothing new, so a burst of changes
+// only reloads the posts once.
+const watchInterval = time.Second
+
+// watcher reports changes to the content of the active site. It is nil if
+// watching is turned off or could not be started.
+var watcher *watch.Watcher
+
+var watchFlag *bool
+
+// watchSite starts watching the content of blog for changes made outside
+// hydra, replacing the watcher of the previous site.
+func watchSite(blog hugo.Blog) {
+	if watcher != nil {
+		watcher.Close()
+		watcher = nil
+	}
+	if !*watchFlag {
+		return
+	}
+	w, err := watch.Watch(blog.ContentDir(), watchInterval)
+	if err != nil {
+		statusMessage = fmt.Sprintf("Not watching the site for changes: %s", err)
+		return
+	}
+	watcher = w
+}
+
+// applyChanges updates the posts with the changes the watcher found since the
+// last command of the REPL.
+func applyChanges(blog hugo.Blog) hugo.Blog {
+	if watcher == nil {
+		return blog
+	}
+	for {
+		select {
+		case files, ok := <-watcher.Changes:
+			if !ok {
+				return blog
+			}
+			if err := blog.FilesChanged(files); err != nil {
+				statusMessage = err.Error()
+			}
+			gitChanged()
+		default:
+			return blog
+		}
+	}
+}